@@ -106,7 +106,7 @@ func (a *App) DeleteBounce(c echo.Context) error {
 
 // BlocklistBouncedSubscribers handles blocklisting of all bounced subscribers.
 func (a *App) BlocklistBouncedSubscribers(c echo.Context) error {
-	if err := a.core.BlocklistBouncedSubscribers(); err != nil {
+	if err := a.core.As(userActor(c)).BlocklistBouncedSubscribers(); err != nil {
 		return err
 	}
 
@@ -136,6 +136,37 @@ func initHTTPHandlers(e *echo.Echo, a *App) {
 		g.DELETE("/api/bounces", pm(a.DeleteBounces, "bounces:manage"))
 		g.DELETE("/api/bounces/:id", pm(hasID(a.DeleteBounce), "bounces:manage"))
 
+		g.GET("/api/webhooks", pm(a.GetWebhooks, "webhooks:get"))
+		g.GET("/api/webhooks/events", pm(a.GetWebhookEvents, "webhooks:get"))
+		g.GET("/api/webhooks/stats", pm(a.GetWebhookStats, "webhooks:get"))
+		g.GET("/api/webhooks/metrics", pm(a.GetWebhookMetrics, "webhooks:get"))
+		g.GET("/api/webhooks/unknown-events", pm(a.GetWebhooksWithUnknownEvents, "webhooks:get"))
+		g.GET("/api/webhooks/logs", pm(a.GetWebhookLogs, "webhooks:get"))
+		g.GET("/api/webhooks/logs/errors", pm(a.GetWebhookLogErrors, "webhooks:get"))
+		g.GET("/api/webhooks/logs/export", pm(a.ExportWebhookLogs, "webhooks:get"))
+		g.GET("/api/webhooks/logs/dead", pm(a.GetDeadLetters, "webhooks:get"))
+		g.POST("/api/webhooks/logs/dead/resubmit", pm(a.ResubmitDeadLetters, "webhooks:manage"))
+		g.GET("/api/webhooks/logs/:id", pm(hasID(a.GetWebhookLog), "webhooks:get"))
+		g.POST("/api/webhooks/logs/retry", pm(a.RetryWebhookLogs, "webhooks:manage"))
+		g.POST("/api/webhooks/logs/:id/retry", pm(hasID(a.RetryWebhookLog), "webhooks:manage"))
+		g.DELETE("/api/webhooks/logs", pm(a.DeleteWebhookLogs, "webhooks:manage"))
+		g.GET("/api/webhooks/:id", pm(hasID(a.GetWebhook), "webhooks:get"))
+		g.POST("/api/webhooks", pm(a.CreateWebhook, "webhooks:manage"))
+		g.POST("/api/webhooks/trigger", pm(a.TriggerCustomWebhookEvent, "webhooks:manage"))
+		g.POST("/api/webhooks/verify", pm(a.VerifyWebhookSignature, "webhooks:get"))
+		g.POST("/api/webhooks/preview-payload", pm(a.PreviewWebhookPayload, "webhooks:manage"))
+		g.POST("/api/webhooks/status", pm(a.SetWebhooksStatus, "webhooks:manage"))
+		g.PUT("/api/webhooks/:id", pm(hasID(a.UpdateWebhook), "webhooks:manage"))
+		g.GET("/api/webhooks/:id/history", pm(hasID(a.GetWebhookHistory), "webhooks:get"))
+		g.GET("/api/webhooks/:id/breaker", pm(hasID(a.GetWebhookBreaker), "webhooks:get"))
+		g.POST("/api/webhooks/:id/test", pm(hasID(a.TestWebhook), "webhooks:manage"))
+		g.POST("/api/webhooks/:id/pause", pm(hasID(a.PauseWebhook), "webhooks:manage"))
+		g.POST("/api/webhooks/:id/resume", pm(hasID(a.ResumeWebhook), "webhooks:manage"))
+		g.POST("/api/webhooks/:id/secret/rotate", pm(hasID(a.RotateWebhookSecret), "webhooks:manage"))
+		g.POST("/api/webhooks/:id/secret/finalize", pm(hasID(a.FinalizeWebhookSecretRotation), "webhooks:manage"))
+		g.DELETE("/api/webhooks", pm(a.DeleteWebhooks, "webhooks:manage"))
+		g.DELETE("/api/webhooks/:id", pm(hasID(a.DeleteWebhook), "webhooks:manage"))
+
 		// Subscriber operations based on arbitrary SQL queries.
 		// These aren't very REST-like.
 		g.POST("/api/subscribers/query/delete", pm(a.DeleteSubscribersByQuery, "subscribers:manage"))
@@ -234,6 +265,9 @@ func initHTTPHandlers(e *echo.Echo, a *App) {
 			g.POST("/webhooks/service/:service", a.BounceWebhook)
 		}
 
+		// Confirmation of webhook deliveries by their receivers.
+		g.POST("/webhooks/confirm/:message_id", a.ConfirmWebhookLog)
+
 		// Landing page.
 		g.GET("/", func(c echo.Context) error {
 			return c.Render(http.StatusOK, "home", publicTpl{Title: "listmonk"})
@@ -47,6 +47,7 @@ import (
 	"github.com/knadh/listmonk/internal/messenger/postback"
 	"github.com/knadh/listmonk/internal/notifs"
 	"github.com/knadh/listmonk/internal/subimporter"
+	"github.com/knadh/listmonk/internal/webhooks"
 	"github.com/knadh/listmonk/models"
 	"github.com/knadh/stuffbin"
 	"github.com/labstack/echo/v4"
@@ -146,6 +147,13 @@ type Config struct {
 	BouncePostmarkEnabled     bool
 	BounceForwardemailEnabled bool
 
+	// host:port (or host for any port) at which listmonk itself is reachable.
+	// Webhooks can't point at these.
+	WebhookSelfHosts []string
+
+	// Whether webhooks can skip TLS certificate verification.
+	WebhookAllowInsecureTLS bool
+
 	PermissionsRaw json.RawMessage
 	Permissions    map[string]struct{}
 }
@@ -485,6 +493,8 @@ func initConstConfig(ko *koanf.Koanf) *Config {
 	c.BouncePostmarkEnabled = ko.Bool("bounce.postmark.enabled")
 	c.BounceForwardemailEnabled = ko.Bool("bounce.forwardemail.enabled")
 	c.HasLegacyUser = ko.Exists("app.admin_username") || ko.Exists("app.admin_password")
+	c.WebhookSelfHosts = makeWebhookSelfHosts(ko)
+	c.WebhookAllowInsecureTLS = ko.Bool("webhooks.allow_insecure_tls")
 
 	b := md5.Sum([]byte(time.Now().String()))
 	c.AssetVersion = fmt.Sprintf("%x", b)[0:10]
@@ -531,11 +541,12 @@ func initI18n(lang string, fs stuffbin.FileSystem) *i18n.I18n {
 }
 
 // initCore initializes the CRUD DB core .
-func initCore(fnNotify func(sub models.Subscriber, listIDs []int) (int, error), queries *models.Queries, db *sqlx.DB, i *i18n.I18n, ko *koanf.Koanf) *core.Core {
+func initCore(fnNotify func(sub models.Subscriber, listIDs []int) (int, error), wh *webhooks.Manager, queries *models.Queries, db *sqlx.DB, i *i18n.I18n, ko *koanf.Koanf) *core.Core {
 	opt := &core.Opt{
 		Constants: core.Constants{
 			SendOptinConfirmation: ko.Bool("app.send_optin_confirmation"),
 			CacheSlowQueries:      ko.Bool("app.cache_slow_queries"),
+			WebhookCampaignStats:  ko.Bool("webhooks.campaign_finished_stats"),
 		},
 		Queries: queries,
 		DB:      db,
@@ -551,6 +562,8 @@ func initCore(fnNotify func(sub models.Subscriber, listIDs []int) (int, error),
 	// Initialize the CRUD core.
 	return core.New(opt, &core.Hooks{
 		SendOptinConfirmation: fnNotify,
+		TriggerWebhook:        wh.Trigger,
+		GetWebhookBreaker:     wh.Breaker,
 	})
 }
 
@@ -579,6 +592,7 @@ func initCampaignManager(msgrs []manager.Messenger, q *models.Queries, u *UrlCon
 		SlidingWindowDuration: ko.Duration("app.message_sliding_window_duration"),
 		SlidingWindowRate:     ko.Int("app.message_sliding_window_rate"),
 		ScanInterval:          time.Second * 5,
+		ProgressStep:          ko.Int("webhooks.campaign_progress_step"),
 		ScanCampaigns:         !ko.Bool("passive"),
 	}, newManagerStore(q, co, md), i, lo)
 
@@ -835,6 +849,85 @@ func initBounceManager(cb func(models.Bounce) error, stmt *sqlx.Stmt, lo *log.Lo
 	return b
 }
 
+// initWebhooks initializes the manager that queues and delivers outgoing webhook events.
+func initWebhooks(cfg *Config, q *models.Queries, lo *log.Logger) *webhooks.Manager {
+	// Configs from before these keys were added don't have them, so fall back to the defaults.
+	var (
+		workers     = 2
+		batchSize   = 50
+		interval    = time.Second * 5
+		lease       = time.Minute * 5
+		stagger     = true
+		jitter      = 0.2
+		spread      = time.Second * 10
+		critWorkers = 1
+		critEvents  = []string{models.EventSubscriberBounced}
+	)
+	if n := ko.Int("webhooks.workers"); n > 0 {
+		workers = n
+	}
+	if n := ko.Int("webhooks.batch_size"); n > 0 {
+		batchSize = n
+	}
+	if d := ko.Duration("webhooks.poll_interval"); d > 0 {
+		interval = d
+	}
+	if d := ko.Duration("webhooks.lease_duration"); d > 0 {
+		lease = d
+	}
+	if ko.Exists("webhooks.stagger") {
+		stagger = ko.Bool("webhooks.stagger")
+	}
+	if ko.Exists("webhooks.retry_jitter") {
+		jitter = ko.Float64("webhooks.retry_jitter")
+	}
+	if ko.Exists("webhooks.trigger_spread") {
+		spread = ko.Duration("webhooks.trigger_spread")
+	}
+
+	// Critical events, eg: bounces, are delivered by a dedicated pool so that they
+	// aren't delayed by a backlog of bulk subscriber events. It's only disabled
+	// by explicitly setting critical_workers to 0.
+	if ko.Exists("webhooks.critical_workers") {
+		critWorkers = ko.Int("webhooks.critical_workers")
+	}
+	if ev := ko.Strings("webhooks.critical_events"); len(ev) > 0 {
+		critEvents = ev
+	}
+
+	var pools []webhooks.Pool
+	if critWorkers > 0 {
+		pools = append(pools, webhooks.Pool{Name: "critical", Workers: critWorkers, Events: critEvents})
+	}
+
+	return webhooks.New(webhooks.Opt{
+		Workers:       workers,
+		Pools:         pools,
+		BatchSize:     batchSize,
+		Interval:      interval,
+		Stagger:       stagger,
+		LeaseDuration: lease,
+		RetryJitter:   jitter,
+		TriggerSpread: spread,
+
+		SelfHosts:        cfg.WebhookSelfHosts,
+		BlockedCIDRs:     makeWebhookBlockedCIDRs(ko),
+		AllowedHosts:     makeWebhookAllowedHosts(ko),
+		RedactPatterns:   makeWebhookRedactPatterns(ko),
+		CompressPayloads: ko.Bool("webhooks.compress_payloads"),
+		ConfirmURL:       strings.TrimSuffix(ko.String("app.root_url"), "/") + "/webhooks/confirm",
+		InsertBatchSize:  ko.Int("webhooks.insert_batch_size"),
+		InsertBatchWait:  ko.Duration("webhooks.insert_batch_wait"),
+		MaxPerEvent:      ko.Int("webhooks.max_webhooks_per_event"),
+		AllowInsecureTLS: ko.Bool("webhooks.allow_insecure_tls"),
+		AttemptHistory:   ko.Bool("webhooks.debug_attempts"),
+		BreakerThreshold: ko.Int("webhooks.breaker.threshold"),
+		BreakerWindow:    ko.Duration("webhooks.breaker.window"),
+		BreakerCooldown:  ko.Duration("webhooks.breaker.cooldown"),
+		TracerProvider:   makeWebhookTracerProvider(ko),
+	}, newWebhookStore(q), lo)
+}
+
 // initAbout initializes the app's /about API endpoint with the app and system info.
 func initAbout(q *models.Queries, db *sqlx.DB) about {
 	var (
@@ -944,7 +1037,7 @@ func initCaptcha() *captcha.Captcha {
 }
 
 // initCron initializes cron jobs for slow query cache refresh and database vacuum.
-func initCron(co *core.Core, db *sqlx.DB) {
+func initCron(co *core.Core, wh *webhooks.Manager, db *sqlx.DB, i *i18n.I18n) {
 	c := cron.New(cron.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
 
 	// Slow query cache cron job.
@@ -983,6 +1076,65 @@ func initCron(co *core.Core, db *sqlx.DB) {
 		}
 	}
 
+	// Webhook delivery digest cron job.
+	if ko.Bool("webhooks.digest.enabled") {
+		intval := ko.String("webhooks.digest.schedule")
+		if intval == "" {
+			lo.Println("error: invalid cron interval string for webhook digest")
+		} else {
+			period, err := time.ParseDuration(ko.String("webhooks.digest.period"))
+			if err != nil || period <= 0 {
+				period = time.Hour * 24
+			}
+
+			var (
+				emails    = ko.Strings("webhooks.digest.emails")
+				webhookID = ko.Int("webhooks.digest.webhook_id")
+			)
+			_, err = c.Add(intval, func() {
+				sendWebhookDigest(co, wh, i, period, emails, webhookID)
+			})
+			if err != nil {
+				lo.Printf("error initializing webhook digest cron: %v", err)
+			} else {
+				lo.Printf("webhook digest cron enabled at interval: %s", intval)
+			}
+		}
+	}
+
+	// Webhook log retention cron job.
+	if ko.Bool("webhooks.retention.enabled") {
+		intval := ko.String("webhooks.retention.schedule")
+		if intval == "" {
+			lo.Println("error: invalid cron interval string for webhook log retention")
+		} else {
+			var (
+				maxAge, _ = time.ParseDuration(ko.String("webhooks.retention.max_age"))
+				maxCount  = ko.Int("webhooks.retention.max_count")
+			)
+
+			// Failed logs are kept as long as the successful ones unless they have their own age.
+			maxAgeFailed := maxAge
+			if s := ko.String("webhooks.retention.max_age_failed"); s != "" {
+				maxAgeFailed, _ = time.ParseDuration(s)
+			}
+
+			_, err := c.Add(intval, func() {
+				n, err := co.PruneWebhookLogs(maxAge, maxAgeFailed, maxCount)
+				if err != nil {
+					lo.Printf("error pruning webhook logs: %v", err)
+					return
+				}
+				lo.Printf("pruned %d webhook logs", n)
+			})
+			if err != nil {
+				lo.Printf("error initializing webhook log retention cron: %v", err)
+			} else {
+				lo.Printf("webhook log retention cron enabled at interval: %s", intval)
+			}
+		}
+	}
+
 	if len(c.Entries()) > 0 {
 		c.Start()
 	}
@@ -103,7 +103,7 @@ func (a *App) CreateList(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("lists.invalidName"))
 	}
 
-	out, err := a.core.CreateList(l)
+	out, err := a.core.As(userActor(c)).CreateList(l)
 	if err != nil {
 		return err
 	}
@@ -135,7 +135,7 @@ func (a *App) UpdateList(c echo.Context) error {
 	}
 
 	// Update the list in the DB.
-	out, err := a.core.UpdateList(id, l)
+	out, err := a.core.As(userActor(c)).UpdateList(id, l)
 	if err != nil {
 		return err
 	}
@@ -155,7 +155,7 @@ func (a *App) DeleteList(c echo.Context) error {
 
 	// Delete the list from the DB.
 	// Pass getAll=true since we've already verified permissions above.
-	if err := a.core.DeleteLists([]int{id}, "", true, nil); err != nil {
+	if err := a.core.As(userActor(c)).DeleteLists([]int{id}, "", true, nil); err != nil {
 		return err
 	}
 
@@ -200,7 +200,7 @@ func (a *App) DeleteLists(c echo.Context) error {
 
 		// Delete the lists from the DB.
 		// Pass getAll=true since we've already verified permissions above.
-		if err := a.core.DeleteLists(ids, "", true, nil); err != nil {
+		if err := a.core.As(userActor(c)).DeleteLists(ids, "", true, nil); err != nil {
 			return err
 		}
 	} else {
@@ -208,7 +208,7 @@ func (a *App) DeleteLists(c echo.Context) error {
 		hasAllPerm, permittedIDs := user.GetPermittedLists(auth.PermTypeManage)
 
 		// Delete the lists from the DB with permission filtering.
-		if err := a.core.DeleteLists(nil, query, hasAllPerm, permittedIDs); err != nil {
+		if err := a.core.As(userActor(c)).DeleteLists(nil, query, hasAllPerm, permittedIDs); err != nil {
 			return err
 		}
 	}
@@ -26,6 +26,7 @@ import (
 	"github.com/knadh/listmonk/internal/media"
 	"github.com/knadh/listmonk/internal/messenger/email"
 	"github.com/knadh/listmonk/internal/subimporter"
+	"github.com/knadh/listmonk/internal/webhooks"
 	"github.com/knadh/listmonk/models"
 	"github.com/knadh/paginator"
 	"github.com/knadh/stuffbin"
@@ -46,6 +47,7 @@ type App struct {
 	auth       *auth.Auth
 	media      media.Store
 	bounce     *bounce.Manager
+	webhooks   *webhooks.Manager
 	captcha    *captcha.Captcha
 	i18n       *i18n.I18n
 	pg         *paginator.Paginator
@@ -56,6 +58,9 @@ type App struct {
 	about         about
 	fnOptinNotify func(models.Subscriber, []int) (int, error)
 
+	// Per-user limiter of webhook test deliveries.
+	webhookTests *webhookTestLimiter
+
 	// Channel for passing reload signals.
 	chReload chan os.Signal
 
@@ -192,8 +197,11 @@ func main() {
 
 		fbOptinNotify = makeOptinNotifyHook(ko.Bool("privacy.unsubscribe_header"), urlCfg, queries, i18n)
 
+		// Outgoing webhook event queue and delivery.
+		wh = initWebhooks(cfg, queries, lo)
+
 		// Crud core.
-		core = initCore(fbOptinNotify, queries, db, i18n, ko)
+		core = initCore(fbOptinNotify, wh, queries, db, i18n, ko)
 
 		// Initialize all messengers, SMTP and postback.
 		msgrs = append(initSMTPMessengers(), initPostbackMessengers(ko)...)
@@ -240,8 +248,12 @@ func main() {
 		go bounce.Run()
 	}
 
+	// Start the webhook delivery workers.
+	go wh.Run()
+	checkWebhookEvents(core)
+
 	// Start cronjobs.
-	initCron(core, db)
+	initCron(core, wh, db, i18n)
 
 	// Start the campaign manager workers. The campaign batches (fetch from DB, push out
 	// messages) get processed at the specified interval.
@@ -263,6 +275,7 @@ func main() {
 		auth:       auth,
 		media:      media,
 		bounce:     bounce,
+		webhooks:   wh,
 		captcha:    initCaptcha(),
 		i18n:       i18n,
 		log:        lo,
@@ -281,6 +294,7 @@ func main() {
 		fnOptinNotify: fbOptinNotify,
 		about:         initAbout(queries, db),
 		chReload:      chReload,
+		webhookTests:  newWebhookTestLimiter(ko.Int("webhooks.test_rate_limit")),
 
 		// If there are no users, then the app needs to prompt for new user setup.
 		needsUserSetup: !hasUsers,
@@ -310,6 +324,9 @@ func main() {
 		// Close the campaign manager.
 		mgr.Close()
 
+		// Stop the webhook delivery workers.
+		wh.Close()
+
 		// Close the DB pool.
 		db.Close()
 
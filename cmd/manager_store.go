@@ -75,8 +75,16 @@ func (s *store) GetCampaign(campID int) (*models.Campaign, error) {
 
 // UpdateCampaignStatus updates a campaign's status.
 func (s *store) UpdateCampaignStatus(campID int, status string) error {
-	_, err := s.queries.UpdateCampaignStatus.Exec(campID, status)
-	return err
+	if _, err := s.queries.UpdateCampaignStatus.Exec(campID, status); err != nil {
+		return err
+	}
+
+	// Trigger the webhook event for the status change, eg: campaign.finished.
+	if c, err := s.GetCampaign(campID); err == nil {
+		s.core.TriggerCampaignStatusWebhook(*c)
+	}
+
+	return nil
 }
 
 // UpdateCampaignCounts updates a campaign's status.
@@ -85,6 +93,12 @@ func (s *store) UpdateCampaignCounts(campID int, toSend int, sent int, lastSubID
 	return err
 }
 
+// NotifyCampaignProgress triggers the campaign.progress webhook event for a
+// running campaign.
+func (s *store) NotifyCampaignProgress(c models.Campaign, sent, toSend, rate int) {
+	s.core.TriggerCampaignProgressWebhook(c, sent, toSend, rate)
+}
+
 // GetAttachment fetches a media attachment blob.
 func (s *store) GetAttachment(mediaID int) (models.Attachment, error) {
 	m, err := s.core.GetMedia(mediaID, "", "", s.media)
@@ -141,8 +155,8 @@ func (s *store) RecordBounce(b models.Bounce) (int64, int, error) {
 
 // BlocklistSubscriber blocklists a subscriber permanently.
 func (s *store) BlocklistSubscriber(id int64) error {
-	_, err := s.queries.BlocklistSubscribers.Exec(pq.Int64Array{id})
-	return err
+	// Blocklist via core so that the subscriber.blocklisted event is triggered.
+	return s.core.BlocklistSubscribers([]int{int(id)})
 }
 
 // DeleteSubscriber deletes a subscriber from the DB.
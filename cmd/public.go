@@ -270,7 +270,7 @@ func (a *App) SubscriptionPrefs(c echo.Context) error {
 		blocklist = a.cfg.Privacy.AllowBlocklist && req.Blocklist
 	)
 	if !req.Manage || blocklist {
-		if err := a.core.UnsubscribeByCampaign(subUUID, campUUID, blocklist); err != nil {
+		if err := a.core.As(subscriberActor(subUUID)).UnsubscribeByCampaign(subUUID, campUUID, blocklist); err != nil {
 			return c.Render(http.StatusInternalServerError, tplMessage,
 				makeMsgTpl(a.i18n.T("public.errorTitle"), "", a.i18n.T("public.errorProcessingRequest")))
 		}
@@ -302,7 +302,7 @@ func (a *App) SubscriptionPrefs(c echo.Context) error {
 	sub.Name = req.Name
 
 	// Update the subscriber properties in the DB.
-	if _, err := a.core.UpdateSubscriber(sub.ID, sub); err != nil {
+	if _, err := a.core.As(subscriberActor(sub.UUID)).UpdateSubscriber(sub.ID, sub); err != nil {
 		return c.Render(http.StatusInternalServerError, tplMessage,
 			makeMsgTpl(a.i18n.T("public.errorTitle"), "", a.i18n.T("public.errorProcessingRequest")))
 	}
@@ -332,7 +332,7 @@ func (a *App) SubscriptionPrefs(c echo.Context) error {
 	}
 
 	// Unsubscribe from lists.
-	if err := a.core.UnsubscribeLists([]int{sub.ID}, nil, unsubUUIDs); err != nil {
+	if err := a.core.As(subscriberActor(sub.UUID)).UnsubscribeLists([]int{sub.ID}, nil, unsubUUIDs); err != nil {
 		return c.Render(http.StatusInternalServerError, tplMessage,
 			makeMsgTpl(a.i18n.T("public.errorTitle"), "", a.i18n.T("public.errorProcessingRequest")))
 
@@ -390,7 +390,7 @@ func (a *App) OptinPage(c echo.Context) error {
 		}
 
 		// Confirm subscriptions in the DB.
-		if err := a.core.ConfirmOptionSubscription(subUUID, req.ListUUIDs, meta); err != nil {
+		if err := a.core.As(subscriberActor(subUUID)).ConfirmOptionSubscription(subUUID, req.ListUUIDs, meta); err != nil {
 			a.log.Printf("error unsubscribing: %v", err)
 			return c.Render(http.StatusInternalServerError, tplMessage,
 				makeMsgTpl(a.i18n.T("public.errorTitle"), "", a.i18n.Ts("public.errorProcessingRequest")))
@@ -597,6 +597,9 @@ func (a *App) SelfExportSubscriberData(c echo.Context) error {
 			makeMsgTpl(a.i18n.T("public.errorTitle"), "", a.i18n.Ts("public.errorProcessingRequest")))
 	}
 
+	// Notify webhooks of the request irrespective of the e-mail's delivery.
+	a.core.As(subscriberActor(subUUID)).TriggerSubscriberDataRequestedWebhook(subUUID, data.Email)
+
 	// Prepare the attachment e-mail.
 	var msg bytes.Buffer
 	if err := notifs.Tpls.ExecuteTemplate(&msg, notifs.TplSubscriberData, data); err != nil {
@@ -643,7 +646,7 @@ func (a *App) WipeSubscriberData(c echo.Context) error {
 	}
 
 	subUUID := c.Param("subUUID")
-	if err := a.core.DeleteSubscribers(nil, []string{subUUID}); err != nil {
+	if err := a.core.As(subscriberActor(subUUID)).DeleteSubscribers(nil, []string{subUUID}); err != nil {
 		a.log.Printf("error wiping subscriber data: %s", err)
 		return c.Render(http.StatusInternalServerError, tplMessage,
 			makeMsgTpl(a.i18n.T("public.errorTitle"), "", a.i18n.Ts("public.errorProcessingRequest")))
@@ -736,7 +739,7 @@ func (a *App) processSubForm(c echo.Context) (bool, error) {
 	}
 
 	// Insert the subscriber into the DB.
-	_, hasOptin, err := a.core.InsertSubscriber(models.Subscriber{
+	_, hasOptin, err := a.core.As(subscriberActor("")).InsertSubscriber(models.Subscriber{
 		Name:   req.Name,
 		Email:  req.Email,
 		Status: models.SubscriberStatusEnabled,
@@ -757,7 +760,7 @@ func (a *App) processSubForm(c echo.Context) (bool, error) {
 		}
 
 		// Update the subscriber's subscriptions in the DB.
-		_, hasOptin, err := a.core.UpdateSubscriberWithLists(sub.ID, sub, nil, listUUIDs, false, false, true)
+		_, hasOptin, err := a.core.As(subscriberActor(sub.UUID)).UpdateSubscriberWithLists(sub.ID, sub, nil, listUUIDs, false, false, true)
 		if err == nil {
 			return hasOptin, nil
 		}
@@ -236,7 +236,7 @@ func (a *App) CreateSubscriber(c echo.Context) error {
 	listIDs := user.FilterListsByPerm(auth.PermTypeManage, req.Lists)
 
 	// Insert the subscriber into the DB.
-	sub, _, err := a.core.InsertSubscriber(req.Subscriber, listIDs, nil, req.PreconfirmSubs, false)
+	sub, _, err := a.core.As(userActor(c)).InsertSubscriber(req.Subscriber, listIDs, nil, req.PreconfirmSubs, false)
 	if err != nil {
 		return err
 	}
@@ -275,7 +275,7 @@ func (a *App) UpdateSubscriber(c echo.Context) error {
 
 	// Update the subscriber in the DB.
 	id := getID(c)
-	out, _, err := a.core.UpdateSubscriberWithLists(id, req.Subscriber, listIDs, nil, req.PreconfirmSubs, true, false)
+	out, _, err := a.core.As(userActor(c)).UpdateSubscriberWithLists(id, req.Subscriber, listIDs, nil, req.PreconfirmSubs, true, false)
 	if err != nil {
 		return err
 	}
@@ -304,7 +304,7 @@ func (a *App) SubscriberSendOptin(c echo.Context) error {
 func (a *App) BlocklistSubscriber(c echo.Context) error {
 	// Update the subscribers in the DB.
 	id := getID(c)
-	if err := a.core.BlocklistSubscribers([]int{id}); err != nil {
+	if err := a.core.As(userActor(c)).BlocklistSubscribers([]int{id}); err != nil {
 		return err
 	}
 
@@ -324,7 +324,7 @@ func (a *App) BlocklistSubscribers(c echo.Context) error {
 	}
 
 	// Update the subscribers in the DB.
-	if err := a.core.BlocklistSubscribers(req.SubscriberIDs); err != nil {
+	if err := a.core.As(userActor(c)).BlocklistSubscribers(req.SubscriberIDs); err != nil {
 		return err
 	}
 
@@ -379,11 +379,11 @@ func (a *App) ManageSubscriberLists(c echo.Context) error {
 	var err error
 	switch req.Action {
 	case "add":
-		err = a.core.AddSubscriptions(subIDs, listIDs, req.Status)
+		err = a.core.As(userActor(c)).AddSubscriptions(subIDs, listIDs, req.Status)
 	case "remove":
-		err = a.core.DeleteSubscriptions(subIDs, listIDs)
+		err = a.core.As(userActor(c)).DeleteSubscriptions(subIDs, listIDs)
 	case "unsubscribe":
-		err = a.core.UnsubscribeLists(subIDs, listIDs, nil)
+		err = a.core.As(userActor(c)).UnsubscribeLists(subIDs, listIDs, nil)
 	default:
 		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("subscribers.invalidAction"))
 	}
@@ -399,7 +399,7 @@ func (a *App) ManageSubscriberLists(c echo.Context) error {
 func (a *App) DeleteSubscriber(c echo.Context) error {
 	// Delete the subscribers from the DB.
 	id := getID(c)
-	if err := a.core.DeleteSubscribers([]int{id}, nil); err != nil {
+	if err := a.core.As(userActor(c)).DeleteSubscribers([]int{id}, nil); err != nil {
 		return err
 	}
 
@@ -420,7 +420,7 @@ func (a *App) DeleteSubscribers(c echo.Context) error {
 	}
 
 	// Delete the subscribers from the DB.
-	if err := a.core.DeleteSubscribers(ids, nil); err != nil {
+	if err := a.core.As(userActor(c)).DeleteSubscribers(ids, nil); err != nil {
 		return err
 	}
 
@@ -493,7 +493,7 @@ func (a *App) BlocklistSubscribersByQuery(c echo.Context) error {
 	}
 
 	// Update the subscribers in the DB.
-	if err := a.core.BlocklistSubscribersByQuery(req.Search, req.Query, req.ListIDs, req.SubscriptionStatus); err != nil {
+	if err := a.core.As(userActor(c)).BlocklistSubscribersByQuery(req.Search, req.Query, req.ListIDs, req.SubscriptionStatus); err != nil {
 		return err
 	}
 
@@ -171,7 +171,7 @@ func (a *App) UpdateTemplate(c echo.Context) error {
 
 	// Update the template in the DB.
 	id := getID(c)
-	out, err := a.core.UpdateTemplate(id, o.Name, o.Subject, []byte(o.Body), o.BodySource)
+	out, err := a.core.As(userActor(c)).UpdateTemplate(id, o.Name, o.Subject, []byte(o.Body), o.BodySource)
 	if err != nil {
 		return err
 	}
@@ -189,7 +189,7 @@ func (a *App) UpdateTemplate(c echo.Context) error {
 func (a *App) TemplateSetDefault(c echo.Context) error {
 	// Update the template in the DB.
 	id := getID(c)
-	if err := a.core.SetDefaultTemplate(id); err != nil {
+	if err := a.core.As(userActor(c)).SetDefaultTemplate(id); err != nil {
 		return err
 	}
 
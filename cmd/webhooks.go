@@ -0,0 +1,1575 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/knadh/koanf/v2"
+	"github.com/knadh/listmonk/internal/auth"
+	"github.com/knadh/listmonk/internal/core"
+	"github.com/knadh/listmonk/internal/i18n"
+	"github.com/knadh/listmonk/internal/notifs"
+	"github.com/knadh/listmonk/internal/webhooks"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/text/encoding/htmlindex"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+const (
+	webhookMaxRetries         = 100
+	webhookMaxInflightRetries = 1000
+
+	webhookDefaultInflightRetries = 10
+
+	// Default clock skew that receivers should accept on signature timestamps.
+	webhookDefaultSignatureTolerance = "5m"
+
+	// Default duration for which the previous secret of a rotation remains valid,
+	// and the length of the secrets that are generated on rotation.
+	webhookDefaultSecretOverlap = 24 * time.Hour
+	webhookSecretLen            = 32
+
+	webhookMaxHeaders = 50
+
+	// Max weight of a webhook in the delivery scheduler.
+	webhookMaxWeight = 100
+
+	// Default number of test deliveries that a user can make per window.
+	webhookTestRateLimit  = 10
+	webhookTestRateWindow = time.Minute
+)
+
+var (
+	reHeaderName = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+	// Headers that are set by listmonk and can't be custom or carry basic auth credentials.
+	webhookReservedHeaders = []string{"Content-Type", "Content-Length", "Host", "Connection", "Transfer-Encoding", "Content-Encoding", "User-Agent"}
+
+	// IP ranges that webhook receivers can't be reached at if webhooks.blocked_cidrs isn't
+	// in the config: loopback, link-local (incl. cloud metadata endpoints), and private ranges.
+	webhookDefaultBlockedCIDRs = []string{"127.0.0.0/8", "::1/128", "169.254.0.0/16", "fe80::/10", "10.0.0.0/8",
+		"172.16.0.0/12", "192.168.0.0/16", "fc00::/7", "0.0.0.0/8", "100.64.0.0/10"}
+)
+
+// GetWebhooks handles retrieval of all webhooks.
+func (a *App) GetWebhooks(c echo.Context) error {
+	out, err := a.core.GetWebhooks()
+	if err != nil {
+		return err
+	}
+
+	for i := range out {
+		out[i] = maskWebhook(out[i])
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// GetWebhook handles retrieval of a single webhook by ID.
+func (a *App) GetWebhook(c echo.Context) error {
+	id := getID(c)
+	out, err := a.core.GetWebhook(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{maskWebhook(out)})
+}
+
+// GetWebhookBreaker returns the state of a webhook's delivery circuit breaker.
+func (a *App) GetWebhookBreaker(c echo.Context) error {
+	out, err := a.core.GetWebhookBreaker(getID(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// GetWebhookEvents returns the list of events that webhooks can subscribe to.
+func (a *App) GetWebhookEvents(c echo.Context) error {
+	return c.JSON(http.StatusOK, okResp{models.AllWebhookEvents()})
+}
+
+// GetWebhookStats returns the delivery backlog of all webhooks.
+func (a *App) GetWebhookStats(c echo.Context) error {
+	out, err := a.core.GetWebhookStats()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// GetWebhookMetrics returns the in-memory delivery metrics of webhooks since listmonk was
+// started, as JSON, or in the Prometheus text format with ?format=prometheus.
+func (a *App) GetWebhookMetrics(c echo.Context) error {
+	out := a.webhooks.Metrics()
+	if c.QueryParam("format") != "prometheus" {
+		return c.JSON(http.StatusOK, okResp{out})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	c.Response().WriteHeader(http.StatusOK)
+	return out.WritePrometheus(c.Response())
+}
+
+// CreateWebhook handles webhook creation.
+func (a *App) CreateWebhook(c echo.Context) error {
+	var w models.Webhook
+	if err := c.Bind(&w); err != nil {
+		return err
+	}
+
+	w, err := a.validateWebhook(w)
+	if err != nil {
+		return err
+	}
+
+	out, err := a.core.CreateWebhook(w, auth.GetUser(c).ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{maskWebhook(out)})
+}
+
+// UpdateWebhook handles webhook modification.
+func (a *App) UpdateWebhook(c echo.Context) error {
+	var w models.Webhook
+	if err := c.Bind(&w); err != nil {
+		return err
+	}
+
+	w, err := a.validateWebhook(w)
+	if err != nil {
+		return err
+	}
+
+	id := getID(c)
+	out, err := a.core.UpdateWebhook(id, w, auth.GetUser(c).ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{maskWebhook(out)})
+}
+
+// SetWebhooksStatus handles bulk enabling or disabling of webhooks.
+func (a *App) SetWebhooksStatus(c echo.Context) error {
+	var req struct {
+		IDs    []int  `json:"ids"`
+		Status string `json:"status"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if len(req.IDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			a.i18n.Ts("globals.messages.errorInvalidIDs", "error", "ids"))
+	}
+	switch req.Status {
+	case models.WebhookStatusEnabled, models.WebhookStatusDisabled, models.WebhookStatusPaused:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "status"))
+	}
+
+	n, err := a.core.SetWebhooksStatus(req.IDs, req.Status, auth.GetUser(c).ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{struct {
+		Count int `json:"count"`
+	}{n}})
+}
+
+// DeleteWebhook handles deletion of a single webhook.
+func (a *App) DeleteWebhook(c echo.Context) error {
+	id := getID(c)
+	if err := a.core.DeleteWebhooks([]int{id}, auth.GetUser(c).ID); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// DeleteWebhooks handles deletion of multiple webhooks by IDs.
+func (a *App) DeleteWebhooks(c echo.Context) error {
+	ids, err := parseStringIDs(c.Request().URL.Query()["id"])
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			a.i18n.Ts("globals.messages.errorInvalidIDs", "error", err.Error()))
+	}
+	if len(ids) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidID"))
+	}
+
+	if err := a.core.DeleteWebhooks(ids, auth.GetUser(c).ID); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// GetWebhookHistory handles retrieval of the audit trail of a webhook's configuration.
+func (a *App) GetWebhookHistory(c echo.Context) error {
+	var (
+		id = getID(c)
+		pg = a.pg.NewFromURL(c.Request().URL.Query())
+	)
+
+	res, total, err := a.core.GetWebhookHistory(id, pg.Offset, pg.Limit)
+	if err != nil {
+		return err
+	}
+
+	out := models.PageResults{
+		Results: res,
+		Total:   total,
+		Page:    pg.Page,
+		PerPage: pg.PerPage,
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// TestWebhook queues a test event for delivery to a webhook. The outcome
+// of the delivery is recorded in the webhook's logs. The request can optionally
+// simulate one of the events with custom data, eg: to test a receiver's handling
+// of campaign.finished, instead of the default webhook.test event. With sync, the
+// event is delivered right away and the receiver's response is returned instead.
+func (a *App) TestWebhook(c echo.Context) error {
+	var (
+		id   = getID(c)
+		user = auth.GetUser(c)
+	)
+
+	var req struct {
+		Event string          `json:"event"`
+		Data  json.RawMessage `json:"data"`
+		Sync  bool            `json:"sync"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if req.Event != "" && !inArray(req.Event, models.AllWebhookEvents()) && !models.IsCustomWebhookEvent(req.Event) {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.invalidEvents"))
+	}
+
+	var data any = map[string]any{"message": "test event from listmonk"}
+	if d := bytes.TrimSpace(req.Data); len(d) > 0 && !bytes.Equal(d, []byte("null")) {
+		if d[0] != '{' {
+			return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "data"))
+		}
+		data = json.RawMessage(d)
+	}
+
+	// Limit test deliveries per user as they send requests to arbitrary URLs.
+	if !a.webhookTests.allow(user.ID) {
+		return echo.NewHTTPError(http.StatusTooManyRequests, a.i18n.T("webhooks.testRateLimited"))
+	}
+
+	w, err := a.core.GetWebhook(id)
+	if err != nil {
+		return err
+	}
+
+	// Check the URL again as the settings may have changed since the webhook was
+	// saved, and fail early for receivers that can only be reached at blocked IPs.
+	u, err := a.validateWebhookURL(w.URL)
+	if err != nil {
+		return err
+	}
+	if err := a.webhooks.CheckURL(c.Request().Context(), u); errors.Is(err, webhooks.ErrBlockedAddr) {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("webhooks.blockedURL", "error", err.Error()))
+	}
+
+	if req.Sync {
+		res, err := a.webhooks.DeliverNow(id, req.Event, data, userActor(c))
+		if err != nil {
+			a.log.Printf("error delivering test webhook: %v", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, a.i18n.T("globals.messages.internalError"))
+		}
+
+		a.core.AuditWebhookTest(id, map[string]any{"sync": true, "status_code": res.StatusCode}, user.ID)
+
+		return c.JSON(http.StatusOK, okResp{res})
+	}
+
+	logID, err := a.webhooks.TriggerTest(id, req.Event, data, userActor(c))
+	if err != nil {
+		a.log.Printf("error triggering test webhook: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			a.i18n.Ts("globals.messages.errorCreating", "name", "{globals.terms.webhookLog}", "error", err.Error()))
+	}
+
+	a.core.AuditWebhookTest(id, map[string]any{"log_id": logID}, user.ID)
+
+	return c.JSON(http.StatusOK, okResp{struct {
+		LogID int `json:"log_id"`
+	}{logID}})
+}
+
+// PreviewWebhookPayload renders the request that a sample event would be delivered
+// with to a webhook with the given (unsaved) settings, with its body, headers, and
+// signature, without saving or sending anything. With the id of an existing webhook,
+// its secrets are used in place of empty ones, as on updating it.
+func (a *App) PreviewWebhookPayload(c echo.Context) error {
+	var req struct {
+		models.Webhook
+		Event string          `json:"event"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if req.Event != "" && !inArray(req.Event, models.AllWebhookEvents()) && !models.IsCustomWebhookEvent(req.Event) {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.invalidEvents"))
+	}
+
+	var data any = map[string]any{"message": "test event from listmonk"}
+	if d := bytes.TrimSpace(req.Data); len(d) > 0 && !bytes.Equal(d, []byte("null")) {
+		if d[0] != '{' {
+			return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "data"))
+		}
+		data = json.RawMessage(d)
+	}
+
+	w, err := a.validateWebhook(req.Webhook)
+	if err != nil {
+		return err
+	}
+
+	// Secrets of a rotation can't be set and are only taken from an existing webhook.
+	w.AuthHMACSecretPrev, w.AuthHMACRotatedAt, w.AuthHMACPrevExpiresAt = "", null.Time{}, null.Time{}
+	if req.ID > 0 {
+		cur, err := a.core.GetWebhook(req.ID)
+		if err != nil {
+			return err
+		}
+		if w.AuthBasicPass == "" {
+			w.AuthBasicPass = cur.AuthBasicPass
+		}
+		if w.AuthHMACSecret == "" {
+			w.AuthHMACSecret = cur.AuthHMACSecret
+		}
+		w.AuthHMACSecretPrev, w.AuthHMACRotatedAt, w.AuthHMACPrevExpiresAt = cur.AuthHMACSecretPrev, cur.AuthHMACRotatedAt, cur.AuthHMACPrevExpiresAt
+	}
+
+	out, err := a.webhooks.Preview(w, req.Event, data, userActor(c))
+	if err != nil {
+		a.log.Printf("error previewing webhook: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, a.i18n.T("globals.messages.internalError"))
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// RotateWebhookSecret replaces the HMAC secret of a webhook with the given secret,
+// or a generated one. Deliveries are signed with both the new and the previous secret
+// for the overlap duration, or until the rotation is finalized, so that receivers
+// can switch to the new secret without rejecting deliveries in the meantime.
+func (a *App) RotateWebhookSecret(c echo.Context) error {
+	var req struct {
+		Secret  string `json:"secret"`
+		Overlap string `json:"overlap"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	overlap := webhookDefaultSecretOverlap
+	if req.Overlap != "" {
+		d, err := time.ParseDuration(req.Overlap)
+		if err != nil || d < time.Minute {
+			return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "overlap"))
+		}
+		overlap = d
+	}
+
+	id := getID(c)
+	w, err := a.core.GetWebhook(id)
+	if err != nil {
+		return err
+	}
+	if w.AuthType != models.WebhookAuthTypeHMAC {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "auth_type"))
+	}
+
+	secret := strings.TrimSpace(req.Secret)
+	if secret == "" {
+		s, err := generateRandomString(webhookSecretLen)
+		if err != nil {
+			a.log.Printf("error generating webhook secret: %v", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, a.i18n.T("globals.messages.internalError"))
+		}
+		secret = s
+	} else if secret == w.AuthHMACSecret {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "secret"))
+	}
+
+	out, err := a.core.RotateWebhookSecret(id, secret, overlap, auth.GetUser(c).ID)
+	if err != nil {
+		return err
+	}
+
+	// The new secret is returned once so that a generated one can be configured on the receiver.
+	return c.JSON(http.StatusOK, okResp{struct {
+		Secret         string                        `json:"secret"`
+		SecretRotation *models.WebhookSecretRotation `json:"secret_rotation"`
+	}{secret, maskWebhook(out).SecretRotation}})
+}
+
+// FinalizeWebhookSecretRotation drops the previous HMAC secret of a webhook's
+// secret rotation once its receiver has switched to the new secret.
+func (a *App) FinalizeWebhookSecretRotation(c echo.Context) error {
+	out, err := a.core.FinalizeWebhookSecretRotation(getID(c), auth.GetUser(c).ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{maskWebhook(out)})
+}
+
+// PauseWebhook pauses the deliveries of a webhook. Its events are still queued
+// and are delivered when it's resumed.
+func (a *App) PauseWebhook(c echo.Context) error {
+	out, err := a.core.PauseWebhook(getID(c), auth.GetUser(c).ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{maskWebhook(out)})
+}
+
+// ResumeWebhook resumes the deliveries of a paused webhook, flushing the events
+// that were queued while it was paused.
+func (a *App) ResumeWebhook(c echo.Context) error {
+	out, err := a.core.ResumeWebhook(getID(c), auth.GetUser(c).ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{maskWebhook(out)})
+}
+
+// unknownEventsWebhook is a webhook that's subscribed to events that don't exist.
+type unknownEventsWebhook struct {
+	ID            int      `json:"id"`
+	UUID          string   `json:"uuid"`
+	Name          string   `json:"name"`
+	Status        string   `json:"status"`
+	UnknownEvents []string `json:"unknown_events"`
+}
+
+// GetWebhooksWithUnknownEvents returns the webhooks that are subscribed to events that
+// don't exist, eg: events that were removed in an upgrade, so that they can be cleaned
+// up. The webhooks are only reported and not changed.
+func (a *App) GetWebhooksWithUnknownEvents(c echo.Context) error {
+	out, err := getWebhooksWithUnknownEvents(a.core)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// getWebhooksWithUnknownEvents returns the webhooks that are subscribed to unknown events.
+func getWebhooksWithUnknownEvents(co *core.Core) ([]unknownEventsWebhook, error) {
+	hooks, err := co.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []unknownEventsWebhook{}
+	for _, w := range hooks {
+		if evs := models.UnknownWebhookEvents(w.Events); len(evs) > 0 {
+			out = append(out, unknownEventsWebhook{ID: w.ID, UUID: w.UUID, Name: w.Name, Status: w.Status, UnknownEvents: evs})
+		}
+	}
+
+	return out, nil
+}
+
+// checkWebhookEvents logs a warning for every webhook that's subscribed to unknown
+// events on startup, eg: after an upgrade that removed events.
+func checkWebhookEvents(co *core.Core) {
+	hooks, err := getWebhooksWithUnknownEvents(co)
+	if err != nil {
+		return
+	}
+
+	for _, w := range hooks {
+		lo.Printf("WARNING: webhook %d (%s) is subscribed to unknown events that are never triggered: %s. "+
+			"See GET /api/webhooks/unknown-events", w.ID, w.Name, strings.Join(w.UnknownEvents, ", "))
+	}
+}
+
+// TriggerCustomWebhookEvent handles triggering of a user-defined (custom.*) event,
+// which is queued for delivery to all the webhooks that are subscribed to it. It's
+// queued like any other event, and not delivered right away, so that an event that's
+// fanned out to many webhooks gets the same insert batching, per-event cap, weighted
+// scheduling, and retry jitter as the built-in events, and its first deliveries are
+// spread out over webhooks.trigger_spread, instead of a burst of deliveries.
+func (a *App) TriggerCustomWebhookEvent(c echo.Context) error {
+	var req struct {
+		Event string          `json:"event"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if !models.IsCustomWebhookEvent(req.Event) {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.invalidEvents"))
+	}
+	if len(req.Data) == 0 {
+		req.Data = json.RawMessage("null")
+	}
+
+	if err := a.webhooks.Trigger(req.Event, req.Data, userActor(c)); err != nil {
+		a.log.Printf("error triggering webhook event %s: %v", req.Event, err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			a.i18n.Ts("globals.messages.errorCreating", "name", "{globals.terms.webhookLog}", "error", err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// parseWebhookLogTimeRange parses the optional RFC3339 created_after and created_before
+// params by which logs are filtered. Empty values are unbounded.
+func (a *App) parseWebhookLogTimeRange(c echo.Context) (null.Time, null.Time, error) {
+	var after, before null.Time
+	for _, f := range []struct {
+		name string
+		t    *null.Time
+	}{{"created_after", &after}, {"created_before", &before}} {
+		if s := c.FormValue(f.name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return after, before, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", f.name))
+			}
+			*f.t = null.TimeFrom(t)
+		}
+	}
+
+	if after.Valid && before.Valid && after.Time.After(before.Time) {
+		return after, before, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "created_after"))
+	}
+
+	return after, before, nil
+}
+
+// ExportWebhookLogs streams the webhook delivery logs that match the filters as CSV.
+func (a *App) ExportWebhookLogs(c echo.Context) error {
+	var (
+		webhookID, _ = strconv.Atoi(c.QueryParam("webhook_id"))
+		status       = c.FormValue("status")
+		event        = c.FormValue("event")
+		msgID        = c.FormValue("message_id")
+		category     = c.FormValue("category")
+	)
+
+	if msgID != "" && !reUUID.MatchString(msgID) {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "message_id"))
+	}
+	switch status {
+	case "", models.WebhookLogStatusPending, models.WebhookLogStatusSuccess, models.WebhookLogStatusFailed, models.WebhookLogStatusExpired:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "status"))
+	}
+
+	// Expand the category to its events.
+	var events []string
+	if category != "" {
+		events = models.WebhookCategoryEvents(category)
+		if len(events) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "category"))
+		}
+	}
+
+	after, before, err := a.parseWebhookLogTimeRange(c)
+	if err != nil {
+		return err
+	}
+
+	exp := a.core.ExportWebhookLogs(webhookID, status, event, events, msgID, after, before, a.cfg.DBBatchSize)
+
+	// Fetch the first batch before writing the headers so that errors are sent as JSON.
+	out, err := exp()
+	if err != nil {
+		return err
+	}
+
+	var (
+		hdr = c.Response().Header()
+		wr  = csv.NewWriter(c.Response())
+	)
+	hdr.Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	hdr.Set(echo.HeaderContentDisposition, "attachment; filename="+
+		fmt.Sprintf("webhook-logs-%s.csv", time.Now().Format("2006-01-02")))
+	hdr.Set("Cache-Control", "no-cache")
+	wr.Write([]string{"id", "webhook_name", "event", "status", "response_code", "attempts", "created_at"})
+
+	// Write and flush the logs batch by batch until there are no more.
+	for len(out) > 0 {
+		for _, r := range out {
+			if err := wr.Write([]string{strconv.Itoa(r.ID), r.WebhookName, r.Event, r.Status,
+				strconv.Itoa(r.ResponseCode), strconv.Itoa(r.Attempts), r.CreatedAt.Format(time.RFC3339)}); err != nil {
+				a.log.Printf("error streaming webhook logs CSV export: %v", err)
+				return nil
+			}
+		}
+		wr.Flush()
+
+		if out, err = exp(); err != nil {
+			a.log.Printf("error streaming webhook logs CSV export: %v", err)
+			return nil
+		}
+	}
+	wr.Flush()
+
+	return nil
+}
+
+// VerifyWebhookSignature checks a webhook signature against a body with the scheme that
+// deliveries are signed with, for testing receivers' implementations. The secret is only
+// used for the check.
+func (a *App) VerifyWebhookSignature(c echo.Context) error {
+	var req struct {
+		Body      string `json:"body"`
+		Signature string `json:"signature"`
+		Timestamp string `json:"timestamp"`
+		Expires   string `json:"expires"`
+		URL       string `json:"url"`
+		Secret    string `json:"secret"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if req.Timestamp == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "timestamp"))
+	}
+	if req.Secret == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "secret"))
+	}
+
+	// The parts are signed in the order timestamp, expiry, and URL, and the ones
+	// that a webhook doesn't sign are skipped.
+	parts := []string{req.Timestamp}
+	if req.Expires != "" {
+		parts = append(parts, req.Expires)
+	}
+	if req.URL != "" {
+		parts = append(parts, req.URL)
+	}
+
+	ok, err := webhooks.VerifySignature(req.Secret, req.Signature, parts, []byte(req.Body))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "signature"))
+	}
+
+	return c.JSON(http.StatusOK, okResp{struct {
+		Valid bool `json:"valid"`
+	}{ok}})
+}
+
+// GetWebhookLogs handles retrieval of webhook delivery logs.
+func (a *App) GetWebhookLogs(c echo.Context) error {
+	var (
+		webhookID, _ = strconv.Atoi(c.QueryParam("webhook_id"))
+		seqStr       = c.FormValue("sequence")
+		status       = c.FormValue("status")
+		event        = c.FormValue("event")
+		key          = c.FormValue("idempotency_key")
+		msgID        = c.FormValue("message_id")
+		category     = c.FormValue("category")
+		search       = strings.TrimSpace(c.FormValue("search"))
+		orderBy      = c.FormValue("order_by")
+		order        = c.FormValue("order")
+
+		pg = a.pg.NewFromURL(c.Request().URL.Query())
+	)
+
+	// The sequence is a number, and the idempotency key and message ID are UUIDs.
+	var sequence int64
+	if seqStr != "" {
+		n, err := strconv.ParseInt(seqStr, 10, 64)
+		if err != nil || n < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "sequence"))
+		}
+		sequence = n
+	}
+	if key != "" && !reUUID.MatchString(key) {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "idempotency_key"))
+	}
+	if msgID != "" && !reUUID.MatchString(msgID) {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "message_id"))
+	}
+
+	// Expand the category to its events.
+	var events []string
+	if category != "" {
+		events = models.WebhookCategoryEvents(category)
+		if len(events) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "category"))
+		}
+	}
+
+	after, before, err := a.parseWebhookLogTimeRange(c)
+	if err != nil {
+		return err
+	}
+
+	res, total, err := a.core.QueryWebhookLogs(webhookID, status, event, events, key, msgID, sequence, after, before, search, orderBy, order, pg.Offset, pg.Limit)
+	if err != nil {
+		return err
+	}
+
+	// No results.
+	if len(res) == 0 {
+		return c.JSON(http.StatusOK, okResp{models.PageResults{Results: []models.WebhookLog{}}})
+	}
+
+	out := models.PageResults{
+		Results: res,
+		Total:   total,
+		Page:    pg.Page,
+		PerPage: pg.PerPage,
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// GetWebhookLog handles retrieval of a single webhook delivery log.
+func (a *App) GetWebhookLog(c echo.Context) error {
+	id := getID(c)
+	out, err := a.core.GetWebhookLog(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// GetWebhookLogErrors handles retrieval of the errors in webhook logs
+// grouped with their counts.
+func (a *App) GetWebhookLogErrors(c echo.Context) error {
+	var (
+		webhookID, _ = strconv.Atoi(c.QueryParam("webhook_id"))
+		status       = c.FormValue("status")
+	)
+
+	out, err := a.core.GetWebhookLogErrors(webhookID, status)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// GetDeadLetters handles retrieval of the failed webhook logs that have exhausted
+// their webhook's retries.
+func (a *App) GetDeadLetters(c echo.Context) error {
+	var (
+		webhookID, _ = strconv.Atoi(c.QueryParam("webhook_id"))
+
+		pg = a.pg.NewFromURL(c.Request().URL.Query())
+	)
+
+	res, total, err := a.core.GetDeadLetterLogs(webhookID, pg.Offset, pg.Limit)
+	if err != nil {
+		return err
+	}
+
+	// No results.
+	if len(res) == 0 {
+		return c.JSON(http.StatusOK, okResp{models.PageResults{Results: []models.WebhookLog{}}})
+	}
+
+	out := models.PageResults{
+		Results: res,
+		Total:   total,
+		Page:    pg.Page,
+		PerPage: pg.PerPage,
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// ResubmitDeadLetters handles bulk resubmission of the given dead letter logs
+// for delivery.
+func (a *App) ResubmitDeadLetters(c echo.Context) error {
+	var req struct {
+		IDs []int `json:"ids"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if len(req.IDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			a.i18n.Ts("globals.messages.errorInvalidIDs", "error", "ids"))
+	}
+
+	n, err := a.core.ResubmitDeadLetters(req.IDs, auth.GetUser(c).ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{struct {
+		Count int `json:"count"`
+	}{n}})
+}
+
+// RetryWebhookLog handles manual retrying of a single failed or expired webhook log.
+func (a *App) RetryWebhookLog(c echo.Context) error {
+	id := getID(c)
+	out, err := a.core.RetryWebhookLog(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// RetryWebhookLogs handles bulk retrying of the failed and expired webhook logs
+// that match the given filters.
+func (a *App) RetryWebhookLogs(c echo.Context) error {
+	f, err := a.parseWebhookLogFilter(c, models.WebhookLogStatusFailed, models.WebhookLogStatusExpired)
+	if err != nil {
+		return err
+	}
+
+	n, err := a.core.RetryWebhookLogs(f.WebhookID, f.Status, f.Event, f.MinCode, f.MaxCode, f.Since, auth.GetUser(c).ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{struct {
+		Count int `json:"count"`
+	}{n}})
+}
+
+// webhookLogFilter is the filter of the bulk actions on webhook logs.
+type webhookLogFilter struct {
+	WebhookID int
+	Status    string
+	Event     string
+
+	// Optional range of response codes.
+	MinCode int
+	MaxCode int
+
+	// Optional creation time since which logs match.
+	Since null.Time
+}
+
+// parseWebhookLogFilter parses the filter of a bulk action on webhook logs from the
+// request. The status can only be one of the given statuses.
+func (a *App) parseWebhookLogFilter(c echo.Context, statuses ...string) (webhookLogFilter, error) {
+	var (
+		f            webhookLogFilter
+		webhookID, _ = strconv.Atoi(c.FormValue("webhook_id"))
+		minCode, _   = strconv.Atoi(c.FormValue("response_code_min"))
+		maxCode, _   = strconv.Atoi(c.FormValue("response_code_max"))
+		status       = c.FormValue("status")
+	)
+
+	if status != "" && !slices.Contains(statuses, status) {
+		return f, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "status"))
+	}
+	if minCode < 0 || maxCode < 0 || (maxCode > 0 && minCode > maxCode) {
+		return f, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "response_code"))
+	}
+
+	// Optional RFC3339 time since which logs match.
+	if s := c.FormValue("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return f, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "since"))
+		}
+		f.Since = null.TimeFrom(t)
+	}
+
+	f.WebhookID, f.Status, f.Event, f.MinCode, f.MaxCode = webhookID, status, c.FormValue("event"), minCode, maxCode
+	return f, nil
+}
+
+// isEmpty returns whether none of the filters are set.
+func (f webhookLogFilter) isEmpty() bool {
+	return f.WebhookID == 0 && f.Status == "" && f.Event == "" && f.MinCode == 0 && f.MaxCode == 0 && !f.Since.Valid
+}
+
+// ConfirmWebhookLog handles the confirmation of a delivery by its receiver at the
+// public confirmation URL that's sent in the payloads of webhooks with a confirm_window.
+func (a *App) ConfirmWebhookLog(c echo.Context) error {
+	var (
+		msgID = c.Param("message_id")
+		token = c.QueryParam("token")
+	)
+	if !reUUID.MatchString(msgID) || token == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.logNotConfirmable"))
+	}
+
+	if err := a.core.ConfirmWebhookLog(msgID, token); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// DeleteWebhookLogs handles deletion of webhook delivery logs.
+func (a *App) DeleteWebhookLogs(c echo.Context) error {
+	all, _ := strconv.ParseBool(c.QueryParam("all"))
+
+	var ids []int
+	if !all {
+		// There are multiple IDs in the query string.
+		res, err := parseStringIDs(c.Request().URL.Query()["id"])
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidID", "error", err.Error()))
+		}
+
+		ids = res
+	}
+
+	// Without IDs, the delivered logs that match the filters are deleted.
+	if !all && len(ids) == 0 {
+		f, err := a.parseWebhookLogFilter(c, models.WebhookLogStatusSuccess, models.WebhookLogStatusFailed, models.WebhookLogStatusExpired)
+		if err != nil {
+			return err
+		}
+		if f.isEmpty() {
+			return echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidID"))
+		}
+
+		n, err := a.core.DeleteWebhookLogsByFilter(f.WebhookID, f.Status, f.Event, f.MinCode, f.MaxCode, f.Since)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, okResp{struct {
+			Count int `json:"count"`
+		}{n}})
+	}
+
+	if err := a.core.DeleteWebhookLogs(ids, all); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// validateWebhook validates and sanitizes the fields of an incoming webhook.
+func (a *App) validateWebhook(w models.Webhook) (models.Webhook, error) {
+	w.Name = strings.TrimSpace(w.Name)
+	if !strHasLen(w.Name, 1, stdInputMaxLen) {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.invalidName"))
+	}
+
+	w.URL = strings.TrimSpace(w.URL)
+	if _, err := a.validateWebhookURL(w.URL); err != nil {
+		return w, err
+	}
+
+	switch w.Status {
+	case "", models.WebhookStatusEnabled, models.WebhookStatusDisabled, models.WebhookStatusPaused:
+	default:
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "status"))
+	}
+
+	if len(w.Events) == 0 || len(models.UnknownWebhookEvents(w.Events)) > 0 {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.invalidEvents"))
+	}
+
+	// Optional lists that the webhook is scoped to.
+	listIDs := make(pq.Int64Array, 0, len(w.ListIDs))
+	for _, id := range w.ListIDs {
+		if id < 1 {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "list_ids"))
+		}
+		if !slices.Contains(listIDs, id) {
+			listIDs = append(listIDs, id)
+		}
+	}
+	w.ListIDs = listIDs
+
+	switch w.AuthType {
+	case "", models.WebhookAuthTypeNone, models.WebhookAuthTypeBasic, models.WebhookAuthTypeHMAC:
+	default:
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.invalidAuthType"))
+	}
+
+	// Header that carries basic auth credentials.
+	w.AuthBasicHeader = http.CanonicalHeaderKey(strings.TrimSpace(w.AuthBasicHeader))
+	if w.AuthBasicHeader == "" {
+		w.AuthBasicHeader = "Authorization"
+	}
+	if !reHeaderName.MatchString(w.AuthBasicHeader) ||
+		inArray(w.AuthBasicHeader, webhookReservedHeaders) ||
+		strings.HasPrefix(w.AuthBasicHeader, "X-Listmonk-") {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "auth_basic_header"))
+	}
+
+	switch w.AuthHMACTimestamp {
+	case "":
+		w.AuthHMACTimestamp = models.WebhookHMACTimestampSend
+	case models.WebhookHMACTimestampSend, models.WebhookHMACTimestampCreated:
+	default:
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "auth_hmac_timestamp"))
+	}
+
+	// Optional TTL of signatures, signed as an expiry.
+	if w.AuthHMACTTL != "" {
+		if d, err := time.ParseDuration(w.AuthHMACTTL); err != nil || d <= 0 {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "auth_hmac_ttl"))
+		}
+	}
+
+	// Clock skew that receivers should accept on signature timestamps, sent in
+	// X-Listmonk-Tolerance. It isn't enforced on the sender's side.
+	if w.SignatureTolerance == "" {
+		w.SignatureTolerance = webhookDefaultSignatureTolerance
+	}
+	if d, err := time.ParseDuration(w.SignatureTolerance); err != nil || d < time.Second {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "signature_tolerance"))
+	}
+
+	switch w.AuthHMACSignURL {
+	case "":
+		w.AuthHMACSignURL = models.WebhookHMACSignURLNone
+	case models.WebhookHMACSignURLNone, models.WebhookHMACSignURLPath, models.WebhookHMACSignURLFull:
+	default:
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "auth_hmac_sign_url"))
+	}
+
+	switch w.BackoffStrategy {
+	case "":
+		w.BackoffStrategy = models.WebhookBackoffExponential
+	case models.WebhookBackoffExponential, models.WebhookBackoffLinear, models.WebhookBackoffFixed:
+	default:
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "backoff_strategy"))
+	}
+
+	if w.RetryInterval != "" {
+		if d, err := time.ParseDuration(w.RetryInterval); err != nil || d < time.Second {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "retry_interval"))
+		}
+	}
+
+	switch w.FieldNaming {
+	case "":
+		w.FieldNaming = models.WebhookFieldNamingSnake
+	case models.WebhookFieldNamingSnake, models.WebhookFieldNamingCamel, models.WebhookFieldNamingCamelNested:
+	default:
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "field_naming"))
+	}
+
+	switch w.PayloadFormat {
+	case "":
+		w.PayloadFormat = models.WebhookPayloadFormatListmonk
+	case models.WebhookPayloadFormatListmonk, models.WebhookPayloadFormatSlack, models.WebhookPayloadFormatDiscord:
+	default:
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "payload_format"))
+	}
+
+	switch w.HTTPMethod {
+	case "":
+		w.HTTPMethod = http.MethodPost
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "http_method"))
+	}
+
+	// Optional charset of the request body. UTF-8 is the default.
+	w.Charset = strings.ToLower(strings.TrimSpace(w.Charset))
+	if w.Charset == "utf-8" || w.Charset == "utf8" {
+		w.Charset = ""
+	}
+	if w.Charset != "" {
+		if _, err := htmlindex.Get(w.Charset); err != nil {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "charset"))
+		}
+	}
+
+	// Optional Content-Type, eg: with an explicit charset or a vendor type.
+	if w.ContentType = strings.TrimSpace(w.ContentType); w.ContentType != "" {
+		mt, params, err := mime.ParseMediaType(w.ContentType)
+		if err != nil || !strings.Contains(mt, "/") || strings.HasPrefix(mt, "/") || strings.HasSuffix(mt, "/") {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "content_type"))
+		}
+
+		// A charset in the Content-Type has to be the body's charset.
+		if cs, ok := params["charset"]; ok {
+			cs = strings.ToLower(cs)
+			if (w.Charset == "" && cs != "utf-8" && cs != "utf8") || (w.Charset != "" && cs != w.Charset) {
+				return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "content_type"))
+			}
+			params["charset"] = cs
+		}
+		w.ContentType = mime.FormatMediaType(mt, params)
+	}
+
+	// Optional success conditions for 2xx response bodies.
+	if w.SuccessBodyRegex != "" {
+		if _, err := regexp.Compile(w.SuccessBodyRegex); err != nil {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "success_body_regex"))
+		}
+	}
+	if w.SuccessBodyJSON != "" {
+		if path, _, ok := strings.Cut(w.SuccessBodyJSON, "="); !ok || path == "" {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "success_body_json"))
+		}
+	}
+
+	// Optional custom headers. listmonk's own headers can't be overridden.
+	if len(w.Headers) > webhookMaxHeaders {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "headers"))
+	}
+	headers := make(models.WebhookHeaders, len(w.Headers))
+	for k, v := range w.Headers {
+		k = http.CanonicalHeaderKey(strings.TrimSpace(k))
+		if !reHeaderName.MatchString(k) ||
+			inArray(k, webhookReservedHeaders) ||
+			strings.HasPrefix(k, "X-Listmonk-") ||
+			strings.ContainsAny(v, "\r\n") ||
+			len(v) > stdInputMaxLen {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "headers"))
+		}
+		headers[k] = v
+	}
+	w.Headers = headers
+
+	// Skipping TLS verification is only allowed if it's explicitly enabled in the config.
+	if w.InsecureSkipVerify && !a.cfg.WebhookAllowInsecureTLS {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.insecureTLSDisabled"))
+	}
+
+	// Optional CA bundle (PEM) that's trusted in addition to the system's CAs.
+	w.CABundle = strings.TrimSpace(w.CABundle)
+	if w.CABundle != "" && !isValidCABundle(w.CABundle) {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "ca_bundle"))
+	}
+
+	// Optional key to wrap the payload in.
+	w.PayloadRootKey = strings.TrimSpace(w.PayloadRootKey)
+	if w.PayloadRootKey != "" && !strHasLen(w.PayloadRootKey, 1, 100) {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "payload_root_key"))
+	}
+
+	// Optional window within which failed deliveries are retried.
+	w.RetryWindow = strings.TrimSpace(w.RetryWindow)
+	w.RetryWindowTZ = strings.TrimSpace(w.RetryWindowTZ)
+	if w.RetryWindow != "" {
+		if _, err := webhooks.ParseRetryWindow(w.RetryWindow, w.RetryWindowTZ); err != nil {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "retry_window"))
+		}
+	} else if w.RetryWindowTZ != "" {
+		if _, err := time.LoadLocation(w.RetryWindowTZ); err != nil {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "retry_window_tz"))
+		}
+	}
+
+	// Optional payload version pin.
+	if w.PayloadVersion != "" && !inArray(w.PayloadVersion, models.WebhookPayloadVersions) {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "payload_version"))
+	}
+
+	switch w.EmptyData {
+	case "":
+		w.EmptyData = models.WebhookEmptyDataNull
+	case models.WebhookEmptyDataNull, models.WebhookEmptyDataObject, models.WebhookEmptyDataSkip:
+	default:
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "empty_data"))
+	}
+
+	if w.MaxRetries < 0 || w.MaxRetries > webhookMaxRetries {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.invalidMaxRetries"))
+	}
+
+	if w.MaxResponseBody < 0 || w.MaxResponseBody > webhooks.MaxResponseBodyLen {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "max_response_body"))
+	}
+
+	// Weight in the delivery scheduler. 0 (unset) is the default weight.
+	if w.Weight == 0 {
+		w.Weight = 1
+	}
+	if w.Weight < 1 || w.Weight > webhookMaxWeight {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "weight"))
+	}
+
+	if w.FailureThreshold < 0 {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "failure_threshold"))
+	}
+
+	if w.MaxInflightRetries == 0 {
+		w.MaxInflightRetries = webhookDefaultInflightRetries
+	}
+	if w.MaxInflightRetries < 1 || w.MaxInflightRetries > webhookMaxInflightRetries {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "max_inflight_retries"))
+	}
+
+	if w.Timeout == "" {
+		w.Timeout = "10s"
+	}
+	if d, err := time.ParseDuration(w.Timeout); err != nil || d <= 0 {
+		return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.invalidTimeout"))
+	}
+
+	// Optional max age of queued events.
+	if w.MaxEventAge != "" {
+		if d, err := time.ParseDuration(w.MaxEventAge); err != nil || d <= 0 {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "max_event_age"))
+		}
+	}
+
+	// A malformed filter would otherwise silently drop the webhook's events.
+	w.FilterExpression = strings.TrimSpace(w.FilterExpression)
+	if w.FilterExpression != "" {
+		if err := webhooks.ValidateFilterExpression(w.FilterExpression); err != nil {
+			return w, echo.NewHTTPError(http.StatusBadRequest,
+				a.i18n.Ts("webhooks.invalidFilterExpression", "error", err.Error()))
+		}
+	}
+
+	// Optional window within which the receiver has to confirm deliveries. Slack
+	// and Discord messages can't carry the confirmation URL.
+	if w.ConfirmWindow != "" {
+		if d, err := time.ParseDuration(w.ConfirmWindow); err != nil || d <= 0 || w.PayloadFormat != models.WebhookPayloadFormatListmonk {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "confirm_window"))
+		}
+	}
+
+	// Optional total deadline for delivering an event, across all its attempts.
+	if w.TotalDeadline != "" {
+		if d, err := time.ParseDuration(w.TotalDeadline); err != nil || d <= 0 {
+			return w, echo.NewHTTPError(http.StatusBadRequest, a.i18n.Ts("globals.messages.invalidFields", "name", "total_deadline"))
+		}
+	}
+
+	return w, nil
+}
+
+// validateWebhookURL validates a webhook's URL and returns it parsed. The URL can be
+// a template that's resolved against every event, in which case, it's checked with
+// its actions replaced by placeholders, and the resolved URLs are checked again on delivery.
+func (a *App) validateWebhookURL(rawURL string) (*url.URL, error) {
+	if webhooks.IsURLTemplate(rawURL) {
+		s, err := webhooks.ValidateURLTemplate(rawURL)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.invalidURL"))
+		}
+		rawURL = s
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.invalidURL"))
+	}
+
+	// A webhook pointing at listmonk itself can trigger events in a loop.
+	if webhooks.IsSelfHost(u, a.cfg.WebhookSelfHosts) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, a.i18n.T("webhooks.selfURL"))
+	}
+
+	return u, nil
+}
+
+// webhookTestLimiter limits the test deliveries that a user can make in a sliding window.
+type webhookTestLimiter struct {
+	sync.Mutex
+	max  int
+	hits map[int][]time.Time
+}
+
+// newWebhookTestLimiter returns a limiter that allows max test deliveries per user
+// per webhookTestRateWindow. If max is < 1, the default limit is used.
+func newWebhookTestLimiter(max int) *webhookTestLimiter {
+	if max < 1 {
+		max = webhookTestRateLimit
+	}
+
+	return &webhookTestLimiter{max: max, hits: make(map[int][]time.Time)}
+}
+
+// allow checks whether a user can make a test delivery, and if so, counts it.
+func (l *webhookTestLimiter) allow(userID int) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	// Drop the hits that have moved out of the window.
+	var (
+		now  = time.Now()
+		hits = l.hits[userID]
+	)
+	for len(hits) > 0 && now.Sub(hits[0]) >= webhookTestRateWindow {
+		hits = hits[1:]
+	}
+
+	if len(hits) >= l.max {
+		l.hits[userID] = hits
+		return false
+	}
+	l.hits[userID] = append(hits, now)
+
+	return true
+}
+
+// userActor returns the authenticated user of a request as the actor of the
+// webhook events that it triggers.
+func userActor(c echo.Context) models.WebhookActor {
+	u := auth.GetUser(c)
+	return models.WebhookActor{Type: models.WebhookActorUser, ID: u.ID, Name: u.Username}
+}
+
+// subscriberActor returns a subscriber as the actor of the webhook events that
+// their actions on public pages trigger.
+func subscriberActor(uuid string) models.WebhookActor {
+	return models.WebhookActor{Type: models.WebhookActorSubscriber, UUID: uuid}
+}
+
+// isValidCABundle checks whether a PEM bundle has at least one certificate
+// and nothing but certificates.
+func isValidCABundle(b string) bool {
+	var (
+		rest = []byte(b)
+		n    = 0
+	)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			return false
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return false
+		}
+		n++
+	}
+
+	return n > 0 && len(bytes.TrimSpace(rest)) == 0
+}
+
+// maskWebhook masks the secrets of a webhook and flags its insecure settings
+// before it's sent out in a response.
+func maskWebhook(w models.Webhook) models.Webhook {
+	w.AuthBasicPass = strings.Repeat(pwdMask, utf8.RuneCountInString(w.AuthBasicPass))
+	w.AuthHMACSecret = strings.Repeat(pwdMask, utf8.RuneCountInString(w.AuthHMACSecret))
+
+	// Expose only whether the secrets of a rotation are set and not the previous secret.
+	if w.AuthType == models.WebhookAuthTypeHMAC {
+		prev := w.PrevHMACSecret(time.Now())
+		w.SecretRotation = &models.WebhookSecretRotation{
+			Primary:   w.AuthHMACSecret != "",
+			Secondary: prev != "",
+			StartedAt: w.AuthHMACRotatedAt,
+		}
+		if prev != "" {
+			w.SecretRotation.ExpiresAt = w.AuthHMACPrevExpiresAt
+		}
+	}
+	w.AuthHMACSecretPrev = ""
+
+	if w.InsecureSkipVerify {
+		w.Warnings = append(w.Warnings, "insecure_skip_verify")
+	}
+
+	return w
+}
+
+// makeWebhookSelfHosts returns the list of hosts at which listmonk itself is
+// reachable, ie: the root URL, the address the server listens on, and the
+// additional hosts in webhooks.self_hosts in the config.
+func makeWebhookSelfHosts(ko *koanf.Koanf) []string {
+	var out []string
+	for _, h := range ko.Strings("webhooks.self_hosts") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			out = append(out, h)
+		}
+	}
+
+	if u, err := url.Parse(ko.String("app.root_url")); err == nil && u.Host != "" {
+		out = append(out, webhooks.URLHostPort(u))
+	}
+
+	host, port, err := net.SplitHostPort(ko.String("app.address"))
+	if err != nil {
+		return out
+	}
+
+	// If the server listens on all interfaces or on loopback, it's reachable on
+	// all the loopback addresses.
+	ip := net.ParseIP(host)
+	if host == "" || host == "localhost" || (ip != nil && (ip.IsUnspecified() || ip.IsLoopback())) {
+		for _, h := range []string{"localhost", "127.0.0.1", "::1"} {
+			out = append(out, net.JoinHostPort(h, port))
+		}
+	} else {
+		out = append(out, net.JoinHostPort(strings.ToLower(host), port))
+	}
+
+	return out
+}
+
+// makeWebhookBlockedCIDRs returns the IP ranges in webhooks.blocked_cidrs in the config
+// that webhook receivers can't be reached at, or the default ranges if the key isn't set.
+// Blocking is only disabled by explicitly setting it to an empty list.
+func makeWebhookBlockedCIDRs(ko *koanf.Koanf) []*net.IPNet {
+	cidrs := webhookDefaultBlockedCIDRs
+	if ko.Exists("webhooks.blocked_cidrs") {
+		cidrs = ko.Strings("webhooks.blocked_cidrs")
+	}
+
+	var out []*net.IPNet
+	for _, s := range cidrs {
+		_, n, err := net.ParseCIDR(strings.TrimSpace(s))
+		if err != nil {
+			lo.Fatalf("invalid CIDR in webhooks.blocked_cidrs: %s", s)
+		}
+		out = append(out, n)
+	}
+
+	return out
+}
+
+// makeWebhookTracerProvider returns an OpenTelemetry tracer provider that exports the
+// spans of webhook deliveries with OTLP over HTTP if webhooks.tracing is enabled in the
+// config, or nil. The exporter is configured with the standard OTEL_EXPORTER_OTLP_*
+// environment variables, eg: OTEL_EXPORTER_OTLP_ENDPOINT.
+func makeWebhookTracerProvider(ko *koanf.Koanf) trace.TracerProvider {
+	if !ko.Bool("webhooks.tracing.enabled") {
+		return nil
+	}
+
+	exp, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		lo.Fatalf("error initializing webhook tracing exporter: %v", err)
+	}
+
+	// The service is named listmonk unless it's overridden with OTEL_SERVICE_NAME.
+	res, err := resource.Merge(resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", "listmonk")))
+	if err == nil {
+		res, err = resource.Merge(res, resource.Environment())
+	}
+	if err != nil {
+		lo.Printf("error initializing webhook tracing resource: %v", err)
+		res = resource.Default()
+	}
+
+	ratio := ko.Float64("webhooks.tracing.sample_ratio")
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	// Trace contexts are propagated to receivers in the traceparent header.
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	lo.Printf("webhook delivery tracing enabled (sample ratio %g)", ratio)
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.TraceIDRatioBased(ratio)),
+	)
+}
+
+// makeWebhookRedactPatterns compiles the patterns in webhooks.redact_response_patterns
+// in the config that are redacted from the recorded response bodies of deliveries.
+func makeWebhookRedactPatterns(ko *koanf.Koanf) []*regexp.Regexp {
+	var out []*regexp.Regexp
+	for _, s := range ko.Strings("webhooks.redact_response_patterns") {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			lo.Fatalf("invalid pattern in webhooks.redact_response_patterns: %s: %v", s, err)
+		}
+		out = append(out, re)
+	}
+
+	return out
+}
+
+// makeWebhookAllowedHosts returns the hosts in webhooks.allowed_hosts in the config
+// that are exempt from webhooks.blocked_cidrs.
+func makeWebhookAllowedHosts(ko *koanf.Koanf) []string {
+	var out []string
+	for _, h := range ko.Strings("webhooks.allowed_hosts") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			out = append(out, h)
+		}
+	}
+
+	return out
+}
+
+// sendWebhookDigest sends out a digest of the delivery health of webhooks over the
+// last period to the given e-mails (or the admin notification e-mails) and, optionally,
+// to a webhook as a webhook.digest event.
+func sendWebhookDigest(co *core.Core, wh *webhooks.Manager, i *i18n.I18n, period time.Duration, emails []string, webhookID int) {
+	out, err := co.GetWebhookDigest(period)
+	if err != nil {
+		lo.Printf("error preparing webhook digest: %v", err)
+		return
+	}
+	if len(out) == 0 {
+		return
+	}
+
+	data := map[string]any{
+		"Period":   period.String(),
+		"Webhooks": out,
+	}
+
+	subject := i.T("email.webhookDigest.subject")
+	if len(emails) > 0 {
+		err = notifs.Notify(emails, subject, notifs.TplWebhookDigest, data, nil)
+	} else {
+		err = notifs.NotifySystem(subject, notifs.TplWebhookDigest, data, nil)
+	}
+	if err != nil {
+		lo.Printf("error sending webhook digest e-mail: %v", err)
+	}
+
+	if webhookID > 0 {
+		if _, err := wh.TriggerWebhook(webhookID, models.EventWebhookDigest, map[string]any{
+			"period":   period.String(),
+			"webhooks": out,
+		}, models.SystemWebhookActor); err != nil {
+			lo.Printf("error sending webhook digest to webhook %d: %v", webhookID, err)
+		}
+	}
+}
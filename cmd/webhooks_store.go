@@ -0,0 +1,145 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/knadh/listmonk/internal/webhooks"
+	"github.com/knadh/listmonk/models"
+	"github.com/lib/pq"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+// webhookStore implements webhooks.Store over the primary database.
+type webhookStore struct {
+	queries *models.Queries
+}
+
+func newWebhookStore(q *models.Queries) *webhookStore {
+	return &webhookStore{
+		queries: q,
+	}
+}
+
+// GetWebhooksByEvent retrieves the enabled webhooks that are subscribed to an event.
+func (s *webhookStore) GetWebhooksByEvent(event string) ([]models.Webhook, error) {
+	var out []models.Webhook
+	err := s.queries.GetWebhooksByEvent.Select(&out, event)
+	return out, err
+}
+
+// GetWebhook retrieves a webhook by its ID.
+func (s *webhookStore) GetWebhook(id int) (models.Webhook, error) {
+	var out []models.Webhook
+	if err := s.queries.GetWebhooks.Select(&out, id); err != nil {
+		return models.Webhook{}, err
+	}
+	if len(out) == 0 {
+		return models.Webhook{}, sql.ErrNoRows
+	}
+
+	return out[0], nil
+}
+
+// CreateLog inserts a pending delivery log.
+func (s *webhookStore) CreateLog(r webhooks.LogRow) (int, error) {
+	var id int
+	err := s.queries.CreateWebhookLog.Get(&id, r.WebhookID, r.Event, r.Key, r.MessageID, r.Payload, r.Test, r.PayloadGZ, r.Delay.Seconds())
+	return id, err
+}
+
+// CreateLogs inserts pending delivery logs in a single statement that assigns them
+// the next numbers in their webhooks' log sequences in order.
+func (s *webhookStore) CreateLogs(rows []webhooks.LogRow) error {
+	var (
+		n        = len(rows)
+		ids      = make([]int, n)
+		events   = make([]string, n)
+		keys     = make([]string, n)
+		msgIDs   = make([]string, n)
+		payloads = make([]string, n)
+		tests    = make([]bool, n)
+		gzs      = make([][]byte, n)
+		delays   = make([]float64, n)
+	)
+	for i, r := range rows {
+		ids[i], events[i], keys[i], msgIDs[i] = r.WebhookID, r.Event, r.Key, r.MessageID
+		payloads[i], tests[i], gzs[i], delays[i] = string(r.Payload), r.Test, r.PayloadGZ, r.Delay.Seconds()
+	}
+
+	_, err := s.queries.CreateWebhookLogs.Exec(pq.Array(ids), pq.StringArray(events), pq.StringArray(keys),
+		pq.StringArray(msgIDs), pq.StringArray(payloads), pq.BoolArray(tests), pq.ByteaArray(gzs), pq.Float64Array(delays))
+	return err
+}
+
+// CreateFailedLog records a delivery log that's failed without being delivered.
+func (s *webhookStore) CreateFailedLog(r webhooks.LogRow, errMsg, errClass string) (int, error) {
+	var id int
+	err := s.queries.CreateFailedWebhookLog.Get(&id, r.WebhookID, r.Event, r.Key, r.MessageID, r.Payload, errMsg, errClass)
+	return id, err
+}
+
+// NextLogs leases and retrieves a batch of due pending logs.
+func (s *webhookStore) NextLogs(limit int, lease time.Duration, events, exclude []string) ([]webhooks.PendingLog, error) {
+	var out []webhooks.PendingLog
+	err := s.queries.GetPendingWebhookLogs.Select(&out, limit, lease.Seconds(), pq.StringArray(events), pq.StringArray(exclude))
+	return out, err
+}
+
+// SetLogConfirmToken stores the confirmation token of a delivery log.
+func (s *webhookStore) SetLogConfirmToken(id int, token string) error {
+	_, err := s.queries.SetWebhookLogConfirmToken.Exec(id, token)
+	return err
+}
+
+// UpdateLogSuccess records a successful delivery.
+func (s *webhookStore) UpdateLogSuccess(l models.WebhookLog, attempts, code int, body string, confirmBy null.Time) error {
+	_, err := s.queries.UpdateWebhookLogSuccess.Exec(l.ID, attempts, code, body, l.PayloadVersion, l.URL, l.ResponseHash, l.ResponseTruncated, confirmBy)
+	return err
+}
+
+// UpdateLogFailed records a failed delivery attempt.
+func (s *webhookStore) UpdateLogFailed(l models.WebhookLog, attempts, code int, body, errMsg, errClass string, next null.Time, expired bool) error {
+	_, err := s.queries.UpdateWebhookLogFailed.Exec(l.ID, attempts, code, body, errMsg, next, l.PayloadVersion, errClass, l.URL, l.ResponseHash, l.ResponseTruncated, expired)
+	return err
+}
+
+// AppendLogAttempt appends a failed attempt to a log's attempt history.
+func (s *webhookStore) AppendLogAttempt(id int, a models.WebhookAttempt) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.queries.AppendWebhookLogAttempt.Exec(id, string(b))
+	return err
+}
+
+// UpdateLogExpired expires a pending log.
+func (s *webhookStore) UpdateLogExpired(id int, msg string) error {
+	_, err := s.queries.UpdateWebhookLogExpired.Exec(id, msg)
+	return err
+}
+
+// DeferLog reschedules a pending log without counting an attempt.
+func (s *webhookStore) DeferLog(id int, until time.Time) error {
+	_, err := s.queries.DeferWebhookLog.Exec(id, until)
+	return err
+}
+
+// RecordFailure counts a permanently failed delivery towards a webhook's consecutive failures.
+func (s *webhookStore) RecordFailure(webhookID int) (int, int, error) {
+	var res struct {
+		Failures  int `db:"consecutive_failures"`
+		Threshold int `db:"failure_threshold"`
+	}
+	err := s.queries.RecordWebhookFailure.Get(&res, webhookID)
+	return res.Failures, res.Threshold, err
+}
+
+// ResetFailures resets the consecutive failures of a webhook.
+func (s *webhookStore) ResetFailures(webhookID int) error {
+	_, err := s.queries.ResetWebhookFailures.Exec(pq.Array([]int{webhookID}))
+	return err
+}
@@ -60,6 +60,8 @@ const (
 	PermBouncesGet            = "bounces:get"
 	PermBouncesManage         = "bounces:manage"
 	PermWebhooksPostBounce    = "webhooks:post_bounce"
+	PermWebhooksGet           = "webhooks:get"
+	PermWebhooksManage        = "webhooks:manage"
 	PermMediaGet              = "media:get"
 	PermMediaManage           = "media:manage"
 	PermTemplatesGet          = "templates:get"
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -63,7 +65,9 @@ func (c *Core) RecordBounce(b models.Bounce) error {
 		return echo.NewHTTPError(http.StatusBadRequest, c.i18n.Ts("globals.messages.invalidData")+": "+b.Type)
 	}
 
-	_, err := c.q.RecordBounce.Exec(b.SubscriberUUID,
+	// The subscriber, if they were blocklisted by the bounce.
+	var blocked []blocklistedSubscriber
+	err := c.q.RecordBounce.Select(&blocked, b.SubscriberUUID,
 		b.Email,
 		b.CampaignUUID,
 		b.Type,
@@ -81,18 +85,31 @@ func (c *Core) RecordBounce(b models.Bounce) error {
 		}
 
 		c.log.Printf("error recording bounce: %v", err)
+		return err
 	}
 
-	return err
+	c.triggerWebhook(models.EventSubscriberBounced, map[string]any{
+		"subscriber":      map[string]any{"uuid": b.SubscriberUUID, "email": b.Email},
+		"campaign":        map[string]any{"uuid": b.CampaignUUID},
+		"source":          b.Source,
+		"type":            b.Type,
+		"diagnostic_code": bounceDiagnosticCode(b.Meta),
+	})
+	c.triggerSubscribersBlocklisted(blocked, models.WebhookBlocklistBounce)
+
+	return nil
 }
 
 // BlocklistBouncedSubscribers blocklists all bounced subscribers.
 func (c *Core) BlocklistBouncedSubscribers() error {
-	if _, err := c.q.BlocklistBouncedSubscribers.Exec(); err != nil {
+	var subs []blocklistedSubscriber
+	if err := c.q.BlocklistBouncedSubscribers.Select(&subs); err != nil {
 		c.log.Printf("error blocklisting bounced subscribers: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, c.i18n.Ts("subscribers.errorBlocklisting", "error", err.Error()))
 	}
 
+	c.triggerSubscribersBlocklisted(subs, models.WebhookBlocklistBounce)
+
 	return nil
 }
 
@@ -110,3 +127,56 @@ func (c *Core) DeleteBounces(ids []int, all bool) error {
 	}
 	return nil
 }
+
+// bounceDiagnosticCode returns the SMTP diagnostic code of a bounce from its meta, where the
+// bounce processors record it in different fields, or an empty string if it has none.
+func bounceDiagnosticCode(meta json.RawMessage) string {
+	// Meta may be anything, eg: an array of notifications from SendGrid, in which case
+	// it has no code.
+	var m map[string]any
+	if err := json.Unmarshal(meta, &m); err != nil {
+		return ""
+	}
+
+	// Bounces recorded with the API can have the code in their meta.
+	if s := metaStr(m["diagnostic_code"]); s != "" {
+		return s
+	}
+
+	// Mailbox: "smtp_status=5.1.1" from classifying the bounce e-mail.
+	if s, ok := strings.CutPrefix(metaStr(m["classify_reason"]), "smtp_status="); ok {
+		return s
+	}
+
+	// SES: the diagnostic code, or the status, of the first bounced recipient.
+	if b, ok := m["bounce"].(map[string]any); ok {
+		if r, ok := b["bouncedRecipients"].([]any); ok && len(r) > 0 {
+			if r, ok := r[0].(map[string]any); ok {
+				if s := metaStr(r["diagnosticCode"]); s != "" {
+					return s
+				}
+				return metaStr(r["status"])
+			}
+		}
+
+		// Forward Email: the status of the bounce.
+		if s := metaStr(b["status"]); s != "" {
+			return s
+		}
+	}
+
+	// Postmark: the details of the bounce, eg: "smtp;550 5.1.1 The email account ..".
+	return metaStr(m["Details"])
+}
+
+// metaStr returns a string or a number in bounce meta as a string.
+func metaStr(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprint(v)
+	}
+
+	return ""
+}
@@ -207,6 +207,8 @@ func (c *Core) CreateCampaign(o models.Campaign, listIDs []int, mediaIDs []int)
 		return models.Campaign{}, err
 	}
 
+	c.triggerWebhook(models.EventCampaignCreated, map[string]any{"campaign": campaignEventData(out)})
+
 	return out, nil
 }
 
@@ -243,6 +245,8 @@ func (c *Core) UpdateCampaign(id int, o models.Campaign, listIDs []int, mediaIDs
 		return models.Campaign{}, err
 	}
 
+	c.triggerWebhook(models.EventCampaignUpdated, map[string]any{"campaign": campaignEventData(out)})
+
 	return out, nil
 }
 
@@ -299,11 +303,18 @@ func (c *Core) UpdateCampaignStatus(id int, status string) (models.Campaign, err
 	}
 
 	cm.Status = status
+	c.TriggerCampaignStatusWebhook(cm)
+
 	return cm, nil
 }
 
 // UpdateCampaignArchive updates a campaign's archive properties.
 func (c *Core) UpdateCampaignArchive(id int, enabled bool, tplID int, meta models.JSON, archiveSlug string) error {
+	cm, err := c.GetCampaign(id, "", "")
+	if err != nil {
+		return err
+	}
+
 	if _, err := c.q.UpdateCampaignArchive.Exec(id, enabled, archiveSlug, tplID, meta); err != nil {
 		c.log.Printf("error updating campaign: %v", err)
 
@@ -311,6 +322,15 @@ func (c *Core) UpdateCampaignArchive(id int, enabled bool, tplID int, meta model
 			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.campaign}", "error", pqErrMsg(err)))
 	}
 
+	// Only fire the event when the campaign is newly archived and not on changes
+	// to the archive settings of an already archived one.
+	if enabled && !cm.Archive {
+		c.triggerWebhook(models.EventCampaignArchived, map[string]any{
+			"campaign": campaignEventData(cm),
+			"stats":    campaignStatsEventData(cm),
+		})
+	}
+
 	return nil
 }
 
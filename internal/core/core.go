@@ -37,6 +37,10 @@ type Core struct {
 	db     *sqlx.DB
 	q      *models.Queries
 	log    *log.Logger
+
+	// Actor to whom the webhook events triggered by the core are attributed.
+	// See As().
+	actor models.WebhookActor
 }
 
 // Constants represents constant config.
@@ -47,11 +51,17 @@ type Constants struct {
 		Action string
 	}
 	CacheSlowQueries bool
+
+	// Count the views, clicks, and bounces of campaigns for the stats
+	// in campaign.finished webhook events.
+	WebhookCampaignStats bool
 }
 
 // Hooks contains external function hooks that are required by the core package.
 type Hooks struct {
 	SendOptinConfirmation func(models.Subscriber, []int) (int, error)
+	TriggerWebhook        func(event string, data any, actor models.WebhookActor) error
+	GetWebhookBreaker     func(webhookID int) models.WebhookBreaker
 }
 
 // Opt contains the controllers required to start the core.
@@ -84,9 +94,19 @@ func New(o *Opt, h *Hooks) *Core {
 		db:     o.DB,
 		q:      o.Queries,
 		log:    o.Log,
+		actor:  models.SystemWebhookActor,
 	}
 }
 
+// As returns a copy of the core that attributes the webhook events it triggers
+// to the given actor, eg: the user making a request. By default, events are
+// attributed to the system.
+func (c *Core) As(actor models.WebhookActor) *Core {
+	cc := *c
+	cc.actor = actor
+	return &cc
+}
+
 // RefreshMatViews refreshes all materialized views.
 func (c *Core) RefreshMatViews(concurrent bool) error {
 	for _, v := range []string{matDashboardCharts, matDashboardCounts, matListSubStats} {
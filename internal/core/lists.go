@@ -173,7 +173,14 @@ func (c *Core) CreateList(l models.List) (models.List, error) {
 			c.i18n.Ts("globals.messages.errorCreating", "name", "{globals.terms.list}", "error", pqErrMsg(err)))
 	}
 
-	return c.GetList(newID, "")
+	out, err := c.GetList(newID, "")
+	if err != nil {
+		return models.List{}, err
+	}
+
+	c.triggerWebhook(models.EventListCreated, map[string]any{"list": listEventData(out)})
+
+	return out, nil
 }
 
 // UpdateList updates a given list.
@@ -190,7 +197,14 @@ func (c *Core) UpdateList(id int, l models.List) (models.List, error) {
 			c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.list}"))
 	}
 
-	return c.GetList(id, "")
+	out, err := c.GetList(id, "")
+	if err != nil {
+		return models.List{}, err
+	}
+
+	c.triggerWebhook(models.EventListUpdated, map[string]any{"list": listEventData(out)})
+
+	return out, nil
 }
 
 // DeleteList deletes a list.
@@ -208,10 +222,16 @@ func (c *Core) DeleteLists(ids []int, query string, getAll bool, permittedIDs []
 		queryStr = makeSearchString(query)
 	}
 
-	if _, err := c.q.DeleteLists.Exec(pq.Array(ids), queryStr, getAll, pq.Array(permittedIDs)); err != nil {
+	var deleted []models.List
+	if err := c.q.DeleteLists.Select(&deleted, pq.Array(ids), queryStr, getAll, pq.Array(permittedIDs)); err != nil {
 		c.log.Printf("error deleting lists: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("globals.messages.errorDeleting", "name", "{globals.terms.lists}", "error", pqErrMsg(err)))
 	}
+
+	for _, l := range deleted {
+		c.triggerWebhook(models.EventListDeleted, map[string]any{"list": listEventData(l)})
+	}
+
 	return nil
 }
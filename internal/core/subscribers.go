@@ -334,6 +334,8 @@ func (c *Core) InsertSubscriber(sub models.Subscriber, listIDs []int, listUUIDs
 		return models.Subscriber{}, false, err
 	}
 
+	c.triggerWebhook(models.EventSubscriberCreated, map[string]any{"subscriber": subscriberEventData(out)})
+
 	hasOptin := false
 	if !preconfirm && c.consts.SendOptinConfirmation {
 		// Send a confirmation e-mail (if there are any double opt-in lists).
@@ -362,7 +364,13 @@ func (c *Core) UpdateSubscriber(id int, sub models.Subscriber) (models.Subscribe
 		}
 	}
 
-	_, err := c.q.UpdateSubscriber.Exec(id,
+	// Previous state of the subscriber for the webhook event.
+	prev, err := c.getSubscriberForWebhook(id)
+	if err != nil {
+		return models.Subscriber{}, err
+	}
+
+	_, err = c.q.UpdateSubscriber.Exec(id,
 		sub.Email,
 		strings.TrimSpace(sub.Name),
 		sub.Status,
@@ -379,6 +387,13 @@ func (c *Core) UpdateSubscriber(id int, sub models.Subscriber) (models.Subscribe
 		return models.Subscriber{}, err
 	}
 
+	c.triggerWebhook(models.EventSubscriberUpdated, map[string]any{
+		"subscriber": subscriberEventData(out),
+		"previous":   subscriberEventData(prev),
+	})
+	c.triggerSubscriberReactivated(prev, out)
+	c.triggerSubscriberStatusBlocklisted(prev, out)
+
 	return out, nil
 }
 
@@ -403,7 +418,13 @@ func (c *Core) UpdateSubscriberWithLists(id int, sub models.Subscriber, listIDs
 		}
 	}
 
-	_, err := c.q.UpdateSubscriberWithLists.Exec(id,
+	// Previous state of the subscriber for the webhook event.
+	prev, err := c.getSubscriberForWebhook(id)
+	if err != nil {
+		return models.Subscriber{}, false, err
+	}
+
+	_, err = c.q.UpdateSubscriberWithLists.Exec(id,
 		sub.Email,
 		strings.TrimSpace(sub.Name),
 		sub.Status,
@@ -423,6 +444,13 @@ func (c *Core) UpdateSubscriberWithLists(id int, sub models.Subscriber, listIDs
 		return models.Subscriber{}, false, err
 	}
 
+	c.triggerWebhook(models.EventSubscriberUpdated, map[string]any{
+		"subscriber": subscriberEventData(out),
+		"previous":   subscriberEventData(prev),
+	})
+	c.triggerSubscriberReactivated(prev, out)
+	c.triggerSubscriberStatusBlocklisted(prev, out)
+
 	hasOptin := false
 	if !preconfirm && c.consts.SendOptinConfirmation {
 		// Send a confirmation e-mail (if there are any double opt-in lists).
@@ -438,23 +466,29 @@ func (c *Core) UpdateSubscriberWithLists(id int, sub models.Subscriber, listIDs
 
 // BlocklistSubscribers blocklists the given list of subscribers.
 func (c *Core) BlocklistSubscribers(subIDs []int) error {
-	if _, err := c.q.BlocklistSubscribers.Exec(pq.Array(subIDs)); err != nil {
+	var subs []blocklistedSubscriber
+	if err := c.q.BlocklistSubscribers.Select(&subs, pq.Array(subIDs)); err != nil {
 		c.log.Printf("error blocklisting subscribers: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("subscribers.errorBlocklisting", "error", err.Error()))
 	}
 
+	c.triggerSubscribersBlocklisted(subs, models.WebhookBlocklistManual)
+
 	return nil
 }
 
 // BlocklistSubscribersByQuery blocklists the given list of subscribers.
 func (c *Core) BlocklistSubscribersByQuery(searchStr, queryExp string, listIDs []int, subStatus string) error {
-	if err := c.q.ExecSubQueryTpl(searchStr, sanitizeSQLExp(queryExp), c.q.BlocklistSubscribersByQuery, listIDs, c.db, subStatus); err != nil {
+	var subs []blocklistedSubscriber
+	if err := c.q.SelectSubQueryTpl(&subs, searchStr, sanitizeSQLExp(queryExp), c.q.BlocklistSubscribersByQuery, listIDs, c.db, subStatus); err != nil {
 		c.log.Printf("error blocklisting subscribers: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("subscribers.errorBlocklisting", "error", pqErrMsg(err)))
 	}
 
+	c.triggerSubscribersBlocklisted(subs, models.WebhookBlocklistManual)
+
 	return nil
 }
 
@@ -473,6 +507,8 @@ func (c *Core) DeleteSubscribers(subIDs []int, subUUIDs []string) error {
 			c.i18n.Ts("globals.messages.errorDeleting", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
 	}
 
+	c.triggerWebhook(models.EventSubscriberDeleted, map[string]any{"subscriber_ids": subIDs, "subscriber_uuids": subUUIDs})
+
 	return nil
 }
 
@@ -490,12 +526,35 @@ func (c *Core) DeleteSubscribersByQuery(searchStr, queryExp string, listIDs []in
 
 // UnsubscribeByCampaign unsubscribes a given subscriber from lists in a given campaign.
 func (c *Core) UnsubscribeByCampaign(subUUID, campUUID string, blocklist bool) error {
-	if _, err := c.q.UnsubscribeByCampaign.Exec(campUUID, subUUID, blocklist); err != nil {
+	listIDs := []int{}
+	if err := c.q.UnsubscribeByCampaign.Select(&listIDs, campUUID, subUUID, blocklist); err != nil {
 		c.log.Printf("error unsubscribing: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
 	}
 
+	// Blocklisting on unsubscribe removes the subscriber from all lists, not just
+	// the campaign's.
+	scope := models.WebhookUnsubscribeScopeLists
+	if blocklist {
+		scope = models.WebhookUnsubscribeScopeAll
+	}
+
+	c.triggerWebhook(models.EventSubscriberUnsubscribed, map[string]any{
+		"subscriber":  map[string]any{"uuid": subUUID},
+		"campaign":    map[string]any{"uuid": campUUID},
+		"scope":       scope,
+		"list_ids":    listIDs,
+		"blocklisted": blocklist,
+	})
+
+	// The subscriber chose to blocklist themselves on unsubscribing.
+	if blocklist && c.h.TriggerWebhook != nil {
+		if sub, err := c.GetSubscriber(0, subUUID, ""); err == nil {
+			c.triggerSubscribersBlocklisted([]blocklistedSubscriber{{UUID: sub.UUID, Email: sub.Email}}, models.WebhookBlocklistUnsubscribe)
+		}
+	}
+
 	return nil
 }
 
@@ -505,12 +564,25 @@ func (c *Core) ConfirmOptionSubscription(subUUID string, listUUIDs []string, met
 		meta = models.JSON{}
 	}
 
+	// Previous state of the subscriber for the webhook event, as confirming can
+	// resubscribe them to lists that they had unsubscribed from.
+	var prev models.Subscriber
+	if c.h.TriggerWebhook != nil {
+		prev, _ = c.GetSubscriber(0, subUUID, "")
+	}
+
 	if _, err := c.q.ConfirmSubscriptionOptin.Exec(subUUID, pq.Array(listUUIDs), meta); err != nil {
 		c.log.Printf("error confirming subscription: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
 	}
 
+	if prev.ID > 0 {
+		if out, err := c.GetSubscriber(prev.ID, "", ""); err == nil {
+			c.triggerSubscriberReactivated(prev, out)
+		}
+	}
+
 	return nil
 }
 
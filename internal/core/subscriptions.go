@@ -24,12 +24,16 @@ func (c *Core) GetSubscriptions(subID int, subUUID string, allLists bool) ([]mod
 
 // AddSubscriptions adds list subscriptions to subscribers.
 func (c *Core) AddSubscriptions(subIDs, listIDs []int, status string) error {
-	if _, err := c.q.AddSubscribersToLists.Exec(pq.Array(subIDs), pq.Array(listIDs), status); err != nil {
+	var subs []models.SubscriptionStatus
+	if err := c.q.AddSubscribersToLists.Select(&subs, pq.Array(subIDs), pq.Array(listIDs), status); err != nil {
 		c.log.Printf("error adding subscriptions: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", err.Error()))
 	}
 
+	c.triggerWebhook(models.EventSubscriberAddedToList, map[string]any{"subscriber_ids": subIDs, "list_ids": listIDs, "status": status,
+		"subscriptions": subs})
+
 	return nil
 }
 
@@ -52,13 +56,17 @@ func (c *Core) AddSubscriptionsByQuery(searchStr, queryExp string, sourceListIDs
 
 // DeleteSubscriptions delete list subscriptions from subscribers.
 func (c *Core) DeleteSubscriptions(subIDs, listIDs []int) error {
-	if _, err := c.q.DeleteSubscriptions.Exec(pq.Array(subIDs), pq.Array(listIDs)); err != nil {
+	var subs []models.SubscriptionStatus
+	if err := c.q.DeleteSubscriptions.Select(&subs, pq.Array(subIDs), pq.Array(listIDs)); err != nil {
 		c.log.Printf("error deleting subscriptions: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", err.Error()))
 
 	}
 
+	c.triggerWebhook(models.EventSubscriberRemovedFromList, map[string]any{"subscriber_ids": subIDs, "list_ids": listIDs,
+		"subscriptions": subs})
+
 	return nil
 }
 
@@ -87,6 +95,13 @@ func (c *Core) UnsubscribeLists(subIDs, listIDs []int, listUUIDs []string) error
 			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", err.Error()))
 	}
 
+	c.triggerWebhook(models.EventSubscriberUnsubscribed, map[string]any{
+		"subscriber_ids": subIDs,
+		"scope":          models.WebhookUnsubscribeScopeLists,
+		"list_ids":       listIDs,
+		"list_uuids":     listUUIDs,
+	})
+
 	return nil
 }
 
@@ -60,7 +60,14 @@ func (c *Core) UpdateTemplate(id int, name, subject string, body []byte, bodySou
 			c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.template}"))
 	}
 
-	return c.GetTemplate(id, false)
+	out, err := c.GetTemplate(id, false)
+	if err != nil {
+		return models.Template{}, err
+	}
+
+	c.triggerWebhook(models.EventTemplateUpdated, map[string]any{"template": templateEventData(out)})
+
+	return out, nil
 }
 
 // SetDefaultTemplate sets a template as default.
@@ -70,6 +77,11 @@ func (c *Core) SetDefaultTemplate(id int) error {
 			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.template}", "error", pqErrMsg(err)))
 	}
 
+	// The template is now the default.
+	if out, err := c.GetTemplate(id, true); err == nil {
+		c.triggerWebhook(models.EventTemplateUpdated, map[string]any{"template": templateEventData(out)})
+	}
+
 	return nil
 }
 
@@ -0,0 +1,1100 @@
+package core
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+var webhookLogQuerySortFields = []string{"id", "event", "status", "attempts", "created_at", "updated_at"}
+
+// Webhook fields that aren't configuration and aren't recorded in the audit trail.
+var webhookAuditSkipFields = []string{"id", "uuid", "created_at", "updated_at", "warnings", "last_error", "last_error_at", "consecutive_failures", "secret_rotation"}
+
+// Webhook fields whose values are redacted in the audit trail.
+var webhookAuditSecretFields = []string{"auth_basic_pass", "auth_hmac_secret", "auth_hmac_secret_prev"}
+
+// campaignStatusEvents maps campaign statuses to the webhook events they trigger.
+var campaignStatusEvents = map[string]string{
+	models.CampaignStatusRunning:   models.EventCampaignStarted,
+	models.CampaignStatusPaused:    models.EventCampaignPaused,
+	models.CampaignStatusCancelled: models.EventCampaignCancelled,
+	models.CampaignStatusFinished:  models.EventCampaignFinished,
+}
+
+// GetWebhooks retrieves all webhooks.
+func (c *Core) GetWebhooks() ([]models.Webhook, error) {
+	out := []models.Webhook{}
+	if err := c.q.GetWebhooks.Select(&out, 0); err != nil {
+		c.log.Printf("error fetching webhooks: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.webhooks}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetWebhook retrieves a webhook by ID.
+func (c *Core) GetWebhook(id int) (models.Webhook, error) {
+	var out []models.Webhook
+	if err := c.q.GetWebhooks.Select(&out, id); err != nil {
+		c.log.Printf("error fetching webhook: %v", err)
+		return models.Webhook{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.webhook}", "error", pqErrMsg(err)))
+	}
+
+	if len(out) == 0 {
+		return models.Webhook{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.webhook}"))
+	}
+
+	return out[0], nil
+}
+
+// GetWebhookBreaker retrieves the state of a webhook's delivery circuit breaker, eg: to
+// show that its deliveries are on hold because the circuit is open.
+func (c *Core) GetWebhookBreaker(id int) (models.WebhookBreaker, error) {
+	if _, err := c.GetWebhook(id); err != nil {
+		return models.WebhookBreaker{}, err
+	}
+
+	if c.h.GetWebhookBreaker == nil {
+		return models.WebhookBreaker{WebhookID: id, State: models.WebhookBreakerClosed}, nil
+	}
+
+	return c.h.GetWebhookBreaker(id), nil
+}
+
+// CreateWebhook creates a new webhook. userID is the user creating it, recorded in the audit trail.
+func (c *Core) CreateWebhook(w models.Webhook, userID int) (models.Webhook, error) {
+	uu, err := uuid.NewV4()
+	if err != nil {
+		c.log.Printf("error generating UUID: %v", err)
+		return models.Webhook{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUUID", "error", err.Error()))
+	}
+
+	if w.Status == "" {
+		w.Status = models.WebhookStatusEnabled
+	}
+	w.HMACAlgorithm = hmacAlgorithm(w.HMACAlgorithm)
+	if w.AuthType == "" {
+		w.AuthType = models.WebhookAuthTypeNone
+	}
+
+	var newID int
+	w.UUID = uu.String()
+	if err := c.q.CreateWebhook.Get(&newID,
+		w.UUID,
+		w.Name,
+		w.URL,
+		w.Status,
+		pq.StringArray(w.Events),
+		w.AuthType,
+		w.AuthBasicUser,
+		w.AuthBasicPass,
+		w.AuthHMACSecret,
+		w.MaxRetries,
+		w.Timeout,
+		w.AuthHMACTimestamp,
+		w.MaxEventAge,
+		w.Debug,
+		w.HTTPMethod,
+		w.PayloadVersion,
+		w.Charset,
+		w.MaxInflightRetries,
+		w.EmptyData,
+		w.DisableKeepAlive,
+		w.SuccessBodyRegex,
+		w.SuccessBodyJSON,
+		w.AuthHMACSignURL,
+		w.AuthBasicHeader,
+		w.CABundle,
+		w.InsecureSkipVerify,
+		w.ContentType,
+		w.PayloadRootKey,
+		w.Headers,
+		w.RetryWindow,
+		w.RetryWindowTZ,
+		w.AuthHMACTTL,
+		w.HMACAlgorithm,
+		w.FieldNaming,
+		w.BackoffStrategy,
+		w.RetryInterval,
+		w.FailureThreshold,
+		w.MaxResponseBody,
+		w.Compress,
+		w.Weight,
+		w.TotalDeadline,
+		w.FilterExpression,
+		w.ConfirmWindow,
+		w.PayloadFormat,
+		w.SignatureTolerance,
+		pq.Int64Array(w.ListIDs)); err != nil {
+		c.log.Printf("error creating webhook: %v", err)
+		return models.Webhook{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{globals.terms.webhook}", "error", pqErrMsg(err)))
+	}
+
+	out, err := c.GetWebhook(newID)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	c.auditWebhook(newID, models.WebhookAuditCreate, models.Webhook{}, out, userID)
+
+	return out, nil
+}
+
+// UpdateWebhook updates a given webhook. Empty secrets and an empty status retain
+// their existing values. userID is the user updating it, recorded in the audit trail.
+func (c *Core) UpdateWebhook(id int, w models.Webhook, userID int) (models.Webhook, error) {
+	prev, err := c.GetWebhook(id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	// An update without a status, eg: from an older client, mustn't re-enable
+	// a disabled or paused webhook.
+	if w.Status == "" {
+		w.Status = prev.Status
+	}
+	w.HMACAlgorithm = hmacAlgorithm(w.HMACAlgorithm)
+	if w.AuthType == "" {
+		w.AuthType = models.WebhookAuthTypeNone
+	}
+
+	res, err := c.q.UpdateWebhook.Exec(id,
+		w.Name,
+		w.URL,
+		w.Status,
+		pq.StringArray(w.Events),
+		w.AuthType,
+		w.AuthBasicUser,
+		w.AuthBasicPass,
+		w.AuthHMACSecret,
+		w.MaxRetries,
+		w.Timeout,
+		w.AuthHMACTimestamp,
+		w.MaxEventAge,
+		w.Debug,
+		w.HTTPMethod,
+		w.PayloadVersion,
+		w.Charset,
+		w.MaxInflightRetries,
+		w.EmptyData,
+		w.DisableKeepAlive,
+		w.SuccessBodyRegex,
+		w.SuccessBodyJSON,
+		w.AuthHMACSignURL,
+		w.AuthBasicHeader,
+		w.CABundle,
+		w.InsecureSkipVerify,
+		w.ContentType,
+		w.PayloadRootKey,
+		w.Headers,
+		w.RetryWindow,
+		w.RetryWindowTZ,
+		w.AuthHMACTTL,
+		w.HMACAlgorithm,
+		w.FieldNaming,
+		w.BackoffStrategy,
+		w.RetryInterval,
+		w.FailureThreshold,
+		w.MaxResponseBody,
+		w.Compress,
+		w.Weight,
+		w.TotalDeadline,
+		w.FilterExpression,
+		w.ConfirmWindow,
+		w.PayloadFormat,
+		w.SignatureTolerance,
+		pq.Int64Array(w.ListIDs))
+	if err != nil {
+		c.log.Printf("error updating webhook: %v", err)
+		return models.Webhook{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.webhook}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.Webhook{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.webhook}"))
+	}
+
+	// Give a re-enabled webhook a clean slate so that it isn't disabled again by its past failures.
+	if prev.Status != models.WebhookStatusEnabled && w.Status == models.WebhookStatusEnabled {
+		if err := c.ResetWebhookFailures([]int{id}); err != nil {
+			return models.Webhook{}, err
+		}
+	}
+
+	out, err := c.GetWebhook(id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	c.auditWebhook(id, models.WebhookAuditUpdate, prev, out, userID)
+
+	return out, nil
+}
+
+// RotateWebhookSecret replaces the HMAC secret of a webhook with the given secret.
+// The current secret is retained as the previous secret, with which deliveries are
+// also signed, for the overlap duration or until the rotation is finalized.
+// userID is the user rotating it, recorded in the audit trail.
+func (c *Core) RotateWebhookSecret(id int, secret string, overlap time.Duration, userID int) (models.Webhook, error) {
+	prev, err := c.GetWebhook(id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	res, err := c.q.RotateWebhookSecret.Exec(id, secret, overlap.Seconds())
+	if err != nil {
+		c.log.Printf("error rotating webhook secret: %v", err)
+		return models.Webhook{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.webhook}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.Webhook{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.webhook}"))
+	}
+
+	out, err := c.GetWebhook(id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	c.auditWebhook(id, models.WebhookAuditRotateSecret, prev, out, userID)
+
+	return out, nil
+}
+
+// FinalizeWebhookSecretRotation drops the previous HMAC secret of a webhook's
+// secret rotation, after which deliveries are signed only with the current secret.
+// userID is the user finalizing it, recorded in the audit trail.
+func (c *Core) FinalizeWebhookSecretRotation(id int, userID int) (models.Webhook, error) {
+	prev, err := c.GetWebhook(id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	if _, err := c.q.FinalizeWebhookSecret.Exec(id); err != nil {
+		c.log.Printf("error finalizing webhook secret rotation: %v", err)
+		return models.Webhook{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.webhook}", "error", pqErrMsg(err)))
+	}
+
+	out, err := c.GetWebhook(id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	c.auditWebhook(id, models.WebhookAuditFinalizeSecret, prev, out, userID)
+
+	return out, nil
+}
+
+// PauseWebhook pauses the deliveries of an enabled webhook. Events are still queued
+// for it, and pending deliveries are held until it's resumed.
+// userID is the user pausing it, recorded in the audit trail.
+func (c *Core) PauseWebhook(id int, userID int) (models.Webhook, error) {
+	return c.switchWebhookStatus(id, models.WebhookStatusEnabled, models.WebhookStatusPaused, models.WebhookAuditPause, "webhooks.notPausable", userID)
+}
+
+// ResumeWebhook resumes the deliveries of a paused webhook, after which the deliveries
+// queued while it was paused are sent. userID is the user resuming it, recorded in the audit trail.
+func (c *Core) ResumeWebhook(id int, userID int) (models.Webhook, error) {
+	return c.switchWebhookStatus(id, models.WebhookStatusPaused, models.WebhookStatusEnabled, models.WebhookAuditResume, "webhooks.notResumable", userID)
+}
+
+// switchWebhookStatus moves a webhook from the from status to the to status,
+// recording it in the audit trail with the given action. If the webhook isn't in the
+// from status, the errMsg i18n message is returned.
+func (c *Core) switchWebhookStatus(id int, from, to, action, errMsg string, userID int) (models.Webhook, error) {
+	prev, err := c.GetWebhook(id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	if prev.Status != from {
+		return models.Webhook{}, echo.NewHTTPError(http.StatusBadRequest, c.i18n.T(errMsg))
+	}
+
+	if _, err := c.q.UpdateWebhooksStatus.Exec(pq.Array([]int{id}), to); err != nil {
+		c.log.Printf("error updating webhook status: %v", err)
+		return models.Webhook{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.webhook}", "error", pqErrMsg(err)))
+	}
+
+	out, err := c.GetWebhook(id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	c.auditWebhook(id, action, prev, out, userID)
+
+	return out, nil
+}
+
+// SetWebhooksStatus sets the status of the given webhooks and returns the number
+// of webhooks that exist and were updated. userID is the user updating them,
+// recorded in the audit trail.
+func (c *Core) SetWebhooksStatus(ids []int, status string, userID int) (int, error) {
+	prev, err := c.getWebhooksByIDs(ids)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := c.q.UpdateWebhooksStatus.Exec(pq.Array(ids), status)
+	if err != nil {
+		c.log.Printf("error updating webhooks status: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.webhooks}", "error", pqErrMsg(err)))
+	}
+
+	if status == models.WebhookStatusEnabled {
+		if err := c.ResetWebhookFailures(ids); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, w := range prev {
+		cur := w
+		cur.Status = status
+		c.auditWebhook(w.ID, models.WebhookAuditUpdate, w, cur, userID)
+	}
+
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// ResetWebhookFailures resets the consecutive failure count of the given webhooks,
+// which is done when they're re-enabled.
+func (c *Core) ResetWebhookFailures(ids []int) error {
+	if _, err := c.q.ResetWebhookFailures.Exec(pq.Array(ids)); err != nil {
+		c.log.Printf("error resetting webhook failures: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.webhooks}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// DeleteWebhooks deletes the given webhooks along with their logs.
+// userID is the user deleting them, recorded in the audit trail.
+func (c *Core) DeleteWebhooks(ids []int, userID int) error {
+	prev, err := c.getWebhooksByIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.q.DeleteWebhooks.Exec(pq.Array(ids)); err != nil {
+		c.log.Printf("error deleting webhooks: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{globals.terms.webhooks}", "error", pqErrMsg(err)))
+	}
+
+	for _, w := range prev {
+		c.auditWebhook(w.ID, models.WebhookAuditDelete, w, models.Webhook{}, userID)
+	}
+
+	return nil
+}
+
+// GetWebhookHistory retrieves the audit trail of a webhook's configuration, most recent first.
+// The trail of deleted webhooks is retained.
+func (c *Core) GetWebhookHistory(id, offset, limit int) ([]models.WebhookAudit, int, error) {
+	out := []models.WebhookAudit{}
+	if err := c.q.GetWebhookHistory.Select(&out, id, offset, limit); err != nil {
+		c.log.Printf("error fetching webhook history: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.webhook}", "error", pqErrMsg(err)))
+	}
+
+	total := 0
+	if len(out) > 0 {
+		total = out[0].Total
+	}
+
+	return out, total, nil
+}
+
+// getWebhooksByIDs retrieves the webhooks with the given IDs. Unknown IDs are ignored.
+func (c *Core) getWebhooksByIDs(ids []int) ([]models.Webhook, error) {
+	all, err := c.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]models.Webhook, 0, len(ids))
+	for _, w := range all {
+		if slices.Contains(ids, w.ID) {
+			out = append(out, w)
+		}
+	}
+
+	return out, nil
+}
+
+// auditWebhook records the change in a webhook's configuration from prev to cur
+// in its audit trail. Errors are logged and don't fail the change itself.
+func (c *Core) auditWebhook(id int, action string, prev, cur models.Webhook, userID int) {
+	changes := webhookChanges(prev, cur)
+
+	// Updates that don't change anything aren't recorded.
+	if action == models.WebhookAuditUpdate && len(changes) == 0 {
+		return
+	}
+
+	b, err := json.Marshal(changes)
+	if err != nil {
+		c.log.Printf("error marshalling webhook audit changes: %v", err)
+		return
+	}
+
+	if _, err := c.q.CreateWebhookAudit.Exec(id, action, b, userID); err != nil {
+		c.log.Printf("error recording webhook audit (%d, %s): %v", id, action, err)
+	}
+}
+
+// AuditWebhookTest records a test delivery to a webhook, with its details, eg: its log ID,
+// in the webhook's audit trail so that the use of test deliveries is visible.
+func (c *Core) AuditWebhookTest(id int, meta map[string]any, userID int) {
+	c.auditWebhookAction(id, models.WebhookAuditTest, meta, userID)
+}
+
+// auditWebhookAction records an action on a webhook that isn't a change in its
+// configuration, with its details, in the webhook's audit trail.
+func (c *Core) auditWebhookAction(id int, action string, meta map[string]any, userID int) {
+	b, _ := json.Marshal(meta)
+	if _, err := c.q.CreateWebhookAudit.Exec(id, action, b, userID); err != nil {
+		c.log.Printf("error recording webhook audit (%d, %s): %v", id, action, err)
+	}
+}
+
+// webhookChanges returns the configuration fields that differ between two
+// states of a webhook as {"field": {"old": .., "new": ..}}. Secrets are redacted.
+func webhookChanges(prev, cur models.Webhook) map[string]any {
+	var (
+		a = webhookFields(prev)
+		b = webhookFields(cur)
+	)
+
+	out := map[string]any{}
+	for _, fields := range []map[string]any{a, b} {
+		for k := range fields {
+			if _, ok := out[k]; ok || slices.Contains(webhookAuditSkipFields, k) {
+				continue
+			}
+
+			oldV, newV := a[k], b[k]
+			if reflect.DeepEqual(oldV, newV) {
+				continue
+			}
+
+			if slices.Contains(webhookAuditSecretFields, k) {
+				oldV, newV = redactSecret(oldV), redactSecret(newV)
+			}
+			out[k] = map[string]any{"old": oldV, "new": newV}
+		}
+	}
+
+	return out
+}
+
+// webhookFields returns the JSON fields of a webhook as a map.
+func webhookFields(w models.Webhook) map[string]any {
+	b, _ := json.Marshal(w)
+
+	out := map[string]any{}
+	_ = json.Unmarshal(b, &out)
+
+	return out
+}
+
+// redactSecret replaces a non-empty secret with a placeholder.
+func redactSecret(v any) any {
+	if s, _ := v.(string); s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// GetWebhookStats retrieves the delivery backlog of all webhooks.
+func (c *Core) GetWebhookStats() ([]models.WebhookStats, error) {
+	out := []models.WebhookStats{}
+	if err := c.q.GetWebhookStats.Select(&out); err != nil {
+		c.log.Printf("error fetching webhook stats: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.webhooks}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// QueryWebhookLogs retrieves paginated webhook delivery logs based on the given params.
+// A log can be looked up exactly by its idempotency key, message ID, or by its webhook's
+// sequence number for reconciling with a receiver's records. events optionally filters
+// logs by any of the given events, eg: the events of a category. It also returns the total
+// number of matching logs.
+func (c *Core) QueryWebhookLogs(webhookID int, status, event string, events []string, idempotencyKey, messageID string, sequence int64, createdAfter, createdBefore null.Time, search, orderBy, order string, offset, limit int) ([]models.WebhookLog, int, error) {
+	if !strSliceContains(orderBy, webhookLogQuerySortFields) {
+		orderBy = "created_at"
+	}
+	if order != SortAsc && order != SortDesc {
+		order = SortDesc
+	}
+
+	out := []models.WebhookLog{}
+	stmt := strings.ReplaceAll(c.q.QueryWebhookLogs, "%order%", "webhook_logs."+orderBy+" "+order)
+	if err := c.db.Select(&out, stmt, 0, webhookID, status, event, idempotencyKey, sequence, messageID, pq.StringArray(events), offset, limit,
+		createdAfter, createdBefore, likeSubstring(search)); err != nil {
+		c.log.Printf("error fetching webhook logs: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+	}
+
+	total := 0
+	if len(out) > 0 {
+		total = out[0].Total
+	}
+
+	for i := range out {
+		c.unpackWebhookLog(&out[i])
+	}
+
+	return out, total, nil
+}
+
+// ExportWebhookLogs returns a function that returns the next batch of the webhook logs
+// that match the filters on every call, in the order of their IDs, and an empty batch
+// at the end. createdAfter and createdBefore are an optional creation time range.
+func (c *Core) ExportWebhookLogs(webhookID int, status, event string, events []string, messageID string, createdAfter, createdBefore null.Time, batchSize int) func() ([]models.WebhookLogExport, error) {
+	id := 0
+	return func() ([]models.WebhookLogExport, error) {
+		var out []models.WebhookLogExport
+		if err := c.q.ExportWebhookLogs.Select(&out, id, webhookID, status, event, pq.StringArray(events), createdAfter, createdBefore, messageID, batchSize); err != nil {
+			c.log.Printf("error exporting webhook logs: %v", err)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError,
+				c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+		}
+		if len(out) == 0 {
+			return nil, nil
+		}
+
+		id = out[len(out)-1].ID
+		return out, nil
+	}
+}
+
+// GetWebhookLog retrieves a single webhook delivery log.
+func (c *Core) GetWebhookLog(id int) (models.WebhookLog, error) {
+	var out []models.WebhookLog
+	stmt := strings.ReplaceAll(c.q.QueryWebhookLogs, "%order%", "webhook_logs.id "+SortAsc)
+	if err := c.db.Select(&out, stmt, id, 0, "", "", "", 0, "", pq.StringArray{}, 0, 1, null.Time{}, null.Time{}, ""); err != nil {
+		c.log.Printf("error fetching webhook log: %v", err)
+		return models.WebhookLog{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+	}
+
+	if len(out) == 0 {
+		return models.WebhookLog{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.webhookLog}"))
+	}
+
+	c.unpackWebhookLog(&out[0])
+
+	return out[0], nil
+}
+
+// unpackWebhookLog decompresses a log's payload if it's stored compressed.
+func (c *Core) unpackWebhookLog(l *models.WebhookLog) {
+	if err := l.UnpackPayload(); err != nil {
+		c.log.Printf("error decompressing payload of webhook log %d: %v", l.ID, err)
+	}
+}
+
+// GetWebhookLogErrors retrieves the errors in webhook logs grouped with their counts.
+func (c *Core) GetWebhookLogErrors(webhookID int, status string) ([]models.WebhookLogError, error) {
+	out := []models.WebhookLogError{}
+	if err := c.q.GetWebhookLogErrors.Select(&out, webhookID, status); err != nil {
+		c.log.Printf("error fetching webhook log errors: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetDeadLetterLogs retrieves paginated dead letters, ie: the failed webhook logs that
+// have exhausted their webhook's retries, optionally of a single webhook. It also returns
+// the total number of dead letters.
+func (c *Core) GetDeadLetterLogs(webhookID, offset, limit int) ([]models.WebhookLog, int, error) {
+	out := []models.WebhookLog{}
+	if err := c.q.GetDeadLetterLogs.Select(&out, webhookID, offset, limit); err != nil {
+		c.log.Printf("error fetching dead letter webhook logs: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+	}
+
+	total := 0
+	if len(out) > 0 {
+		total = out[0].Total
+	}
+
+	for i := range out {
+		c.unpackWebhookLog(&out[i])
+	}
+
+	return out, total, nil
+}
+
+// ResubmitDeadLetters moves the given dead letters back to pending for delivery with
+// a fresh set of attempts. The resubmissions are recorded in the audit trails of the
+// webhooks with the user who made them. It returns the number of logs resubmitted.
+func (c *Core) ResubmitDeadLetters(ids []int, userID int) (int, error) {
+	var counts []webhookLogCount
+	if err := c.q.ResubmitDeadLetters.Select(&counts, pq.Array(ids)); err != nil {
+		c.log.Printf("error resubmitting dead letter webhook logs: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+	}
+
+	n := 0
+	for _, r := range counts {
+		n += r.Count
+		c.auditWebhookAction(r.WebhookID, models.WebhookAuditResubmitLogs, map[string]any{"count": r.Count}, userID)
+	}
+
+	return n, nil
+}
+
+// GetWebhookDigest retrieves the delivery health of all webhooks over the given period.
+func (c *Core) GetWebhookDigest(period time.Duration) ([]models.WebhookDigest, error) {
+	out := []models.WebhookDigest{}
+	if err := c.q.GetWebhookDigest.Select(&out, period.Seconds()); err != nil {
+		c.log.Printf("error fetching webhook digest: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// RetryWebhookLog moves a failed or expired webhook log back to pending so that
+// it's picked up for delivery again without re-triggering its event.
+func (c *Core) RetryWebhookLog(id int) (models.WebhookLog, error) {
+	l, err := c.GetWebhookLog(id)
+	if err != nil {
+		return models.WebhookLog{}, err
+	}
+
+	// Pending logs are already queued, successful ones were delivered, and
+	// the ones whose data couldn't be marshalled have nothing to deliver.
+	if (l.Status != models.WebhookLogStatusFailed && l.Status != models.WebhookLogStatusExpired) ||
+		l.ErrorClass == models.WebhookErrorPayload {
+		return models.WebhookLog{}, echo.NewHTTPError(http.StatusBadRequest, c.i18n.T("webhooks.logNotRetryable"))
+	}
+
+	res, err := c.q.RetryWebhookLog.Exec(id)
+	if err != nil {
+		c.log.Printf("error retrying webhook log: %v", err)
+		return models.WebhookLog{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+	}
+
+	// The log changed in the meantime.
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.WebhookLog{}, echo.NewHTTPError(http.StatusBadRequest, c.i18n.T("webhooks.logNotRetryable"))
+	}
+
+	return c.GetWebhookLog(id)
+}
+
+// ConfirmWebhookLog marks a delivered webhook log as confirmed by its receiver, given
+// the log's message ID and the token from its confirmation URL.
+func (c *Core) ConfirmWebhookLog(messageID, token string) error {
+	var id int
+	if err := c.q.ConfirmWebhookLog.Get(&id, messageID, token); err != nil {
+		if err == sql.ErrNoRows {
+			return echo.NewHTTPError(http.StatusBadRequest, c.i18n.T("webhooks.logNotConfirmable"))
+		}
+
+		c.log.Printf("error confirming webhook log: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// RetryWebhookLogs moves the failed and expired webhook logs that match the filters back
+// to pending for delivery. minCode and maxCode are an optional range of response codes,
+// and since, an optional creation time. The retries are recorded in the audit trails of
+// the webhooks with the user who made them. It returns the number of logs retried.
+func (c *Core) RetryWebhookLogs(webhookID int, status, event string, minCode, maxCode int, since null.Time, userID int) (int, error) {
+	var counts []webhookLogCount
+	if err := c.q.RetryWebhookLogs.Select(&counts, webhookID, status, event, minCode, maxCode, since); err != nil {
+		c.log.Printf("error retrying webhook logs: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+	}
+
+	n := 0
+	for _, r := range counts {
+		n += r.Count
+		c.auditWebhookAction(r.WebhookID, models.WebhookAuditRetryLogs, map[string]any{
+			"count":             r.Count,
+			"status":            status,
+			"event":             event,
+			"response_code_min": minCode,
+			"response_code_max": maxCode,
+			"since":             since,
+		}, userID)
+	}
+
+	return n, nil
+}
+
+// webhookLogCount is the number of logs of a webhook that a bulk action affected.
+type webhookLogCount struct {
+	WebhookID int `db:"webhook_id"`
+	Count     int `db:"count"`
+}
+
+// DeleteWebhookLogs deletes the given webhook logs or all logs.
+func (c *Core) DeleteWebhookLogs(ids []int, all bool) error {
+	if _, err := c.q.DeleteWebhookLogs.Exec(pq.Array(ids), all); err != nil {
+		c.log.Printf("error deleting webhook logs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// DeleteWebhookLogsByFilter deletes the delivered (non-pending) webhook logs that match
+// the filters, as with RetryWebhookLogs. It returns the number of logs deleted.
+func (c *Core) DeleteWebhookLogsByFilter(webhookID int, status, event string, minCode, maxCode int, since null.Time) (int, error) {
+	res, err := c.q.DeleteWebhookLogsByFilter.Exec(webhookID, status, event, minCode, maxCode, since)
+	if err != nil {
+		c.log.Printf("error deleting webhook logs: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+	}
+
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// PruneWebhookLogs deletes successful webhook logs that are older than maxAge, failed and
+// expired logs that are older than maxAgeFailed, and logs that are beyond the most recent
+// maxCount logs of their webhook. Any of them can be 0 to disable it. It returns the number
+// of logs deleted.
+func (c *Core) PruneWebhookLogs(maxAge, maxAgeFailed time.Duration, maxCount int) (int, error) {
+	res, err := c.q.PruneWebhookLogs.Exec(maxAge.Seconds(), maxCount, maxAgeFailed.Seconds())
+	if err != nil {
+		c.log.Printf("error pruning webhook logs: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{globals.terms.webhookLog}", "error", pqErrMsg(err)))
+	}
+
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// TriggerCampaignStatusWebhook triggers the webhook event corresponding to
+// a campaign's (new) status, if there's one.
+func (c *Core) TriggerCampaignStatusWebhook(cm models.Campaign) {
+	ev, ok := campaignStatusEvents[cm.Status]
+	if !ok {
+		return
+	}
+	c.loadCampaignLists(&cm)
+
+	data := map[string]any{"campaign": campaignEventData(cm)}
+
+	// The final stats of a finished campaign. The send counters are stored on the campaign,
+	// while the views, clicks, and bounces are counted (by the indexed campaign ID), which
+	// is skipped unless it's enabled, as it can be slow for large campaigns.
+	if ev == models.EventCampaignFinished {
+		if c.consts.WebhookCampaignStats {
+			camps := models.Campaigns{cm}
+			if err := camps.LoadStats(c.q.GetCampaignStats); err != nil {
+				c.log.Printf("error fetching campaign stats for webhook: %v", err)
+			} else {
+				cm = camps[0]
+			}
+		}
+
+		st := campaignStatsEventData(cm)
+		if !c.consts.WebhookCampaignStats {
+			delete(st, "views")
+			delete(st, "clicks")
+			delete(st, "bounces")
+		}
+		data["stats"] = st
+	}
+
+	c.triggerWebhook(ev, data)
+}
+
+// TriggerCampaignProgressWebhook triggers the campaign.progress event with the number
+// of messages of a running campaign that have been sent and its send rate per minute.
+func (c *Core) TriggerCampaignProgressWebhook(cm models.Campaign, sent, toSend, rate int) {
+	pct := 0
+	if toSend > 0 {
+		pct = sent * 100 / toSend
+	}
+	c.loadCampaignLists(&cm)
+
+	c.triggerWebhook(models.EventCampaignProgress, map[string]any{
+		"campaign": campaignEventData(cm),
+		"progress": map[string]any{
+			"sent":    sent,
+			"to_send": toSend,
+			"percent": pct,
+			"rate":    rate,
+		},
+	})
+}
+
+// TriggerSubscriberDataRequestedWebhook triggers the subscriber.data_requested event
+// when a subscriber requests an export of their data, eg: for routing subject access
+// requests to an external system.
+func (c *Core) TriggerSubscriberDataRequestedWebhook(uuid, email string) {
+	c.triggerWebhook(models.EventSubscriberDataRequested, map[string]any{
+		"subscriber": map[string]any{
+			"uuid":  uuid,
+			"email": email,
+		},
+	})
+}
+
+// triggerSubscriberReactivated triggers the subscriber.reactivated event if a subscriber
+// has moved from blocklisted back to enabled, or if any of their list subscriptions
+// has moved from unsubscribed back to unconfirmed or confirmed. Unlike subscriber.created,
+// it's only fired for existing subscribers.
+func (c *Core) triggerSubscriberReactivated(prev, cur models.Subscriber) {
+	if c.h.TriggerWebhook == nil {
+		return
+	}
+
+	var prevLists, curLists []models.List
+	_ = json.Unmarshal(prev.Lists, &prevLists)
+	_ = json.Unmarshal(cur.Lists, &curLists)
+
+	// Subscription statuses before the update.
+	unsubbed := map[int]bool{}
+	for _, l := range prevLists {
+		if l.SubscriptionStatus == models.SubscriptionStatusUnsubscribed {
+			unsubbed[l.ID] = true
+		}
+	}
+
+	lists := []map[string]any{}
+	for _, l := range curLists {
+		if unsubbed[l.ID] && l.SubscriptionStatus != models.SubscriptionStatusUnsubscribed {
+			lists = append(lists, map[string]any{
+				"id":                  l.ID,
+				"uuid":                l.UUID,
+				"name":                l.Name,
+				"subscription_status": l.SubscriptionStatus,
+			})
+		}
+	}
+
+	unblocked := prev.Status == models.SubscriberStatusBlockListed && cur.Status == models.SubscriberStatusEnabled
+	if !unblocked && len(lists) == 0 {
+		return
+	}
+
+	c.triggerWebhook(models.EventSubscriberReactivated, map[string]any{
+		"subscriber":      subscriberEventData(cur),
+		"previous_status": prev.Status,
+		"lists":           lists,
+	})
+}
+
+// blocklistedSubscriber is a subscriber that's been blocklisted, as returned by the
+// blocklist queries, for the subscriber.blocklisted event.
+type blocklistedSubscriber struct {
+	UUID  string `db:"uuid"`
+	Email string `db:"email"`
+}
+
+// triggerSubscribersBlocklisted triggers the subscriber.blocklisted event for each of the
+// given subscribers. reason is one of the models.WebhookBlocklist* reasons. Unlike
+// subscriber.unsubscribed, it's only fired when a subscriber's status becomes blocklisted.
+func (c *Core) triggerSubscribersBlocklisted(subs []blocklistedSubscriber, reason string) {
+	for _, s := range subs {
+		c.triggerWebhook(models.EventSubscriberBlocklisted, map[string]any{
+			"subscriber": map[string]any{"uuid": s.UUID, "email": s.Email},
+			"reason":     reason,
+		})
+	}
+}
+
+// triggerSubscriberStatusBlocklisted triggers the subscriber.blocklisted event if a
+// subscriber's status has been changed to blocklisted by an update.
+func (c *Core) triggerSubscriberStatusBlocklisted(prev, cur models.Subscriber) {
+	if c.h.TriggerWebhook == nil || prev.Status == models.SubscriberStatusBlockListed || cur.Status != models.SubscriberStatusBlockListed {
+		return
+	}
+
+	c.triggerSubscribersBlocklisted([]blocklistedSubscriber{{UUID: cur.UUID, Email: cur.Email}}, models.WebhookBlocklistManual)
+}
+
+// triggerWebhook passes an event to the webhook hook, if one is set.
+func (c *Core) triggerWebhook(event string, data any) {
+	if c.h.TriggerWebhook == nil {
+		return
+	}
+
+	if err := c.h.TriggerWebhook(event, data, c.actor); err != nil {
+		c.log.Printf("error triggering webhook event %s: %v", event, err)
+	}
+}
+
+// getSubscriberForWebhook fetches a subscriber's current state to be sent along
+// with an update event. It's a no-op if webhooks aren't enabled.
+func (c *Core) getSubscriberForWebhook(id int) (models.Subscriber, error) {
+	if c.h.TriggerWebhook == nil {
+		return models.Subscriber{}, nil
+	}
+
+	return c.GetSubscriber(id, "", "")
+}
+
+// subscriberEventData returns the subscriber fields that are sent in webhook events:
+// the full subscriber with its attributes and list subscriptions. The subscriptions'
+// meta, which can have the subscriber's IP from opt-in confirmations, isn't sent.
+func subscriberEventData(s models.Subscriber) map[string]any {
+	var subLists []models.List
+	_ = json.Unmarshal(s.Lists, &subLists)
+
+	lists := make([]map[string]any, 0, len(subLists))
+	for _, l := range subLists {
+		lists = append(lists, map[string]any{
+			"id":                      l.ID,
+			"uuid":                    l.UUID,
+			"name":                    l.Name,
+			"type":                    l.Type,
+			"optin":                   l.Optin,
+			"subscription_status":     l.SubscriptionStatus,
+			"subscription_created_at": l.SubscriptionCreatedAt,
+			"subscription_updated_at": l.SubscriptionUpdatedAt,
+		})
+	}
+
+	attribs := s.Attribs
+	if attribs == nil {
+		attribs = models.JSON{}
+	}
+
+	return map[string]any{
+		"id":         s.ID,
+		"uuid":       s.UUID,
+		"email":      s.Email,
+		"name":       s.Name,
+		"attribs":    attribs,
+		"status":     s.Status,
+		"lists":      lists,
+		"created_at": s.CreatedAt,
+		"updated_at": s.UpdatedAt,
+	}
+}
+
+// campaignEventData returns the campaign fields that are sent in webhook events,
+// with its lists ({id, name}) if they're loaded.
+func campaignEventData(cm models.Campaign) map[string]any {
+	var lists []map[string]any
+	if err := json.Unmarshal(cm.Lists, &lists); err != nil || lists == nil {
+		lists = []map[string]any{}
+	}
+
+	return map[string]any{
+		"id":     cm.ID,
+		"uuid":   cm.UUID,
+		"name":   cm.Name,
+		"status": cm.Status,
+		"lists":  lists,
+	}
+}
+
+// loadCampaignLists loads the lists of a campaign that's fetched without them, eg: by
+// the campaign manager, for the list scopes of webhooks. It's a no-op if webhooks
+// aren't enabled or the lists are already loaded.
+func (c *Core) loadCampaignLists(cm *models.Campaign) {
+	if c.h.TriggerWebhook == nil || len(cm.Lists) > 0 {
+		return
+	}
+
+	if err := c.q.GetCampaignLists.Get(&cm.Lists, cm.ID); err != nil {
+		c.log.Printf("error fetching campaign lists for webhook: %v", err)
+	}
+}
+
+// campaignStatsEventData returns the final delivery stats of a campaign that are
+// sent in webhook events.
+func campaignStatsEventData(cm models.Campaign) map[string]any {
+	return map[string]any{
+		"to_send": cm.ToSend,
+		"sent":    cm.Sent,
+		"views":   cm.Views,
+		"clicks":  cm.Clicks,
+		"bounces": cm.Bounces,
+	}
+}
+
+// listEventData returns the list fields that are sent in webhook events.
+func listEventData(l models.List) map[string]any {
+	return map[string]any{
+		"id":    l.ID,
+		"uuid":  l.UUID,
+		"name":  l.Name,
+		"type":  l.Type,
+		"optin": l.Optin,
+	}
+}
+
+// templateEventData returns the template fields that are sent in webhook events.
+func templateEventData(t models.Template) map[string]any {
+	return map[string]any{
+		"id":         t.ID,
+		"name":       t.Name,
+		"type":       t.Type,
+		"is_default": t.IsDefault,
+	}
+}
+
+// likeSubstring returns an ILIKE pattern that matches the given string anywhere,
+// with its wildcards escaped, or an empty string if it's empty.
+func likeSubstring(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s) + "%"
+}
+
+// hmacAlgorithm returns the given HMAC algorithm if it's known, or sha256.
+func hmacAlgorithm(alg string) string {
+	switch alg {
+	case models.WebhookHMACSHA256, models.WebhookHMACSHA512, models.WebhookHMACSHA1:
+		return alg
+	}
+
+	return models.WebhookHMACSHA256
+}
@@ -39,6 +39,7 @@ type Store interface {
 	GetAttachment(mediaID int) (models.Attachment, error)
 	UpdateCampaignStatus(campID int, status string) error
 	UpdateCampaignCounts(campID int, toSend int, sent int, lastSubID int) error
+	NotifyCampaignProgress(c models.Campaign, sent, toSend, rate int)
 	CreateLink(url string) (string, error)
 	BlocklistSubscriber(id int64) error
 	DeleteSubscriber(id int64) error
@@ -135,6 +136,10 @@ type Config struct {
 	// Interval to scan the DB for active campaign checkpoints.
 	ScanInterval time.Duration
 
+	// Percentage of a running campaign's messages after which its progress
+	// is reported (campaign.progress), eg: 10 for every 10%. 0 disables it.
+	ProgressStep int
+
 	// ScanCampaigns indicates whether this instance of manager will scan the DB
 	// for active campaigns and process them.
 	// This can be used to run multiple instances of listmonk
@@ -156,6 +161,9 @@ func New(cfg Config, store Store, i *i18n.I18n, l *log.Logger) *Manager {
 	if cfg.MessageRate < 1 {
 		cfg.MessageRate = 1
 	}
+	if cfg.ProgressStep < 0 || cfg.ProgressStep >= 100 {
+		cfg.ProgressStep = 0
+	}
 
 	m := &Manager{
 		cfg:   cfg,
@@ -413,6 +421,11 @@ func (m *Manager) scanCampaigns(tick time.Duration) {
 			continue
 		}
 
+		// Report the progress of the campaigns being processed.
+		if m.cfg.ProgressStep > 0 {
+			m.notifyProgress()
+		}
+
 		for _, c := range campaigns {
 			// Create a new pipe that'll handle this campaign's states.
 			p, err := m.newPipe(c)
@@ -517,6 +530,7 @@ func (m *Manager) worker() {
 					}
 					msg.pipe.rate.Incr(1)
 					msg.pipe.sent.Add(1)
+					msg.pipe.totalSent.Add(1)
 				}
 			}
 
@@ -557,6 +571,40 @@ func (m *Manager) getCurrentCampaigns() ([]int64, []int64) {
 	return ids, counts
 }
 
+// notifyProgress reports the progress of the campaigns being processed that
+// have crossed their next ProgressStep percentage since the last report.
+// The final 100% is left to the campaign's status change.
+func (m *Manager) notifyProgress() {
+	type progress struct {
+		camp         *models.Campaign
+		sent, toSend int
+		rate         int
+	}
+
+	m.pipesMut.RLock()
+	var out []progress
+	for _, p := range m.pipes {
+		toSend := p.camp.ToSend
+		if toSend < 1 || p.stopped.Load() {
+			continue
+		}
+
+		sent := int(p.totalSent.Load())
+		step := (sent * 100 / toSend) / m.cfg.ProgressStep * m.cfg.ProgressStep
+		if step <= p.progress || step >= 100 {
+			continue
+		}
+		p.progress = step
+
+		out = append(out, progress{camp: p.camp, sent: sent, toSend: toSend, rate: int(p.rate.Rate())})
+	}
+	m.pipesMut.RUnlock()
+
+	for _, p := range out {
+		m.store.NotifyCampaignProgress(*p.camp, p.sent, p.toSend, p.rate)
+	}
+}
+
 // trackLink register a URL and return its UUID to be used in message templates
 // for tracking links.
 func (m *Manager) trackLink(url, campUUID, subUUID string) string {
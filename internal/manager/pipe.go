@@ -15,11 +15,16 @@ type pipe struct {
 	rate       *ratecounter.RateCounter
 	wg         *sync.WaitGroup
 	sent       atomic.Int64
+	totalSent  atomic.Int64
 	lastID     atomic.Uint64
 	errors     atomic.Uint64
 	stopped    atomic.Bool
 	withErrors atomic.Bool
 
+	// Last reported progress percentage (campaign.progress). It's only
+	// accessed by the campaign scanner.
+	progress int
+
 	m *Manager
 }
 
@@ -49,6 +54,12 @@ func (m *Manager) newPipe(c *models.Campaign) (*pipe, error) {
 		m:    m,
 	}
 
+	// The campaign may be resuming, eg: after a pause or a restart.
+	p.totalSent.Store(int64(c.Sent))
+	if c.ToSend > 0 && m.cfg.ProgressStep > 0 {
+		p.progress = (c.Sent * 100 / c.ToSend) / m.cfg.ProgressStep * m.cfg.ProgressStep
+	}
+
 	// Increment the waitgroup so that Wait() blocks immediately. This is necessary
 	// as a campaign pipe is created first and subscribers/messages under it are
 	// fetched asynchronolusly later. The messages each add to the wg and that
@@ -54,5 +54,211 @@ func V6_0_0(db *sqlx.DB, fs stuffbin.FileSystem, ko *koanf.Koanf, lo *log.Logger
 		return err
 	}
 
+	// Add outgoing webhooks and their delivery logs.
+	_, err = db.Exec(`
+		DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'webhook_status') THEN
+				CREATE TYPE webhook_status AS ENUM ('enabled', 'disabled', 'paused');
+			END IF;
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'webhook_auth_type') THEN
+				CREATE TYPE webhook_auth_type AS ENUM ('none', 'basic', 'hmac');
+			END IF;
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'webhook_log_status') THEN
+				CREATE TYPE webhook_log_status AS ENUM ('pending', 'success', 'failed', 'expired');
+			END IF;
+		END $$;
+
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id               SERIAL PRIMARY KEY,
+			uuid uuid        NOT NULL UNIQUE,
+			name             TEXT NOT NULL,
+			url              TEXT NOT NULL,
+			status           webhook_status NOT NULL DEFAULT 'enabled',
+			events           TEXT[] NOT NULL DEFAULT '{}',
+			list_ids         INTEGER[] NOT NULL DEFAULT '{}',
+			auth_type        webhook_auth_type NOT NULL DEFAULT 'none',
+			auth_basic_user  TEXT NOT NULL DEFAULT '',
+			auth_basic_pass  TEXT NOT NULL DEFAULT '',
+			auth_hmac_secret TEXT NOT NULL DEFAULT '',
+			max_retries      INTEGER NOT NULL DEFAULT 3,
+			timeout          TEXT NOT NULL DEFAULT '10s',
+			auth_hmac_timestamp TEXT NOT NULL DEFAULT 'send',
+			max_event_age    TEXT NOT NULL DEFAULT '',
+			debug            BOOLEAN NOT NULL DEFAULT false,
+			http_method      TEXT NOT NULL DEFAULT 'POST',
+			payload_version  TEXT NOT NULL DEFAULT '',
+			charset          TEXT NOT NULL DEFAULT '',
+			max_inflight_retries INTEGER NOT NULL DEFAULT 10,
+			empty_data       TEXT NOT NULL DEFAULT 'null',
+			disable_keep_alive BOOLEAN NOT NULL DEFAULT false,
+			success_body_regex TEXT NOT NULL DEFAULT '',
+			success_body_json TEXT NOT NULL DEFAULT '',
+			auth_hmac_sign_url TEXT NOT NULL DEFAULT 'none',
+			auth_basic_header TEXT NOT NULL DEFAULT 'Authorization',
+			ca_bundle        TEXT NOT NULL DEFAULT '',
+			insecure_skip_verify BOOLEAN NOT NULL DEFAULT false,
+			content_type     TEXT NOT NULL DEFAULT '',
+			payload_root_key TEXT NOT NULL DEFAULT '',
+			headers          JSONB NOT NULL DEFAULT '{}',
+			retry_window     TEXT NOT NULL DEFAULT '',
+			retry_window_tz  TEXT NOT NULL DEFAULT '',
+			auth_hmac_ttl    TEXT NOT NULL DEFAULT '',
+			hmac_algorithm   TEXT NOT NULL DEFAULT 'sha256',
+			field_naming     TEXT NOT NULL DEFAULT 'snake',
+			backoff_strategy TEXT NOT NULL DEFAULT 'exponential',
+			retry_interval   TEXT NOT NULL DEFAULT '',
+			failure_threshold INTEGER NOT NULL DEFAULT 0,
+			max_response_body INTEGER NOT NULL DEFAULT 0,
+			compress         BOOLEAN NOT NULL DEFAULT false,
+			weight           INTEGER NOT NULL DEFAULT 1,
+			total_deadline   TEXT NOT NULL DEFAULT '',
+			filter_expression TEXT NOT NULL DEFAULT '',
+			confirm_window   TEXT NOT NULL DEFAULT '',
+			payload_format   TEXT NOT NULL DEFAULT 'listmonk',
+			signature_tolerance TEXT NOT NULL DEFAULT '5m',
+			log_sequence     BIGINT NOT NULL DEFAULT 0,
+			consecutive_failures INTEGER NOT NULL DEFAULT 0,
+			auth_hmac_secret_prev TEXT NOT NULL DEFAULT '',
+			auth_hmac_rotated_at TIMESTAMP WITH TIME ZONE NULL,
+			auth_hmac_prev_expires_at TIMESTAMP WITH TIME ZONE NULL,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		-- Columns added after the first form of the table, for databases that already have it.
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS list_ids INTEGER[] NOT NULL DEFAULT '{}';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS auth_hmac_timestamp TEXT NOT NULL DEFAULT 'send';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS max_event_age TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS debug BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS http_method TEXT NOT NULL DEFAULT 'POST';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS payload_version TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS charset TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS max_inflight_retries INTEGER NOT NULL DEFAULT 10;
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS empty_data TEXT NOT NULL DEFAULT 'null';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS disable_keep_alive BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS success_body_regex TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS success_body_json TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS auth_hmac_sign_url TEXT NOT NULL DEFAULT 'none';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS auth_basic_header TEXT NOT NULL DEFAULT 'Authorization';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS ca_bundle TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS insecure_skip_verify BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS content_type TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS payload_root_key TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS headers JSONB NOT NULL DEFAULT '{}';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS retry_window TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS retry_window_tz TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS auth_hmac_ttl TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS hmac_algorithm TEXT NOT NULL DEFAULT 'sha256';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS field_naming TEXT NOT NULL DEFAULT 'snake';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS backoff_strategy TEXT NOT NULL DEFAULT 'exponential';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS retry_interval TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS failure_threshold INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS max_response_body INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS compress BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS weight INTEGER NOT NULL DEFAULT 1;
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS total_deadline TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS filter_expression TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS confirm_window TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS payload_format TEXT NOT NULL DEFAULT 'listmonk';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS signature_tolerance TEXT NOT NULL DEFAULT '5m';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS auth_hmac_secret_prev TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS auth_hmac_rotated_at TIMESTAMP WITH TIME ZONE NULL;
+		ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS auth_hmac_prev_expires_at TIMESTAMP WITH TIME ZONE NULL;
+
+		CREATE INDEX IF NOT EXISTS idx_webhooks_events ON webhooks USING GIN(events);
+		CREATE INDEX IF NOT EXISTS idx_webhooks_list_ids ON webhooks USING GIN(list_ids);
+
+		CREATE TABLE IF NOT EXISTS webhook_logs (
+			id               BIGSERIAL PRIMARY KEY,
+			webhook_id       INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			event            TEXT NOT NULL,
+			status           webhook_log_status NOT NULL DEFAULT 'pending',
+			idempotency_key  uuid NOT NULL UNIQUE,
+			message_id       uuid NOT NULL UNIQUE,
+			sequence         BIGINT NOT NULL DEFAULT 0,
+			payload          JSONB NOT NULL DEFAULT '{}',
+			payload_gz       BYTEA NULL,
+			payload_version  TEXT NOT NULL DEFAULT '',
+			url              TEXT NOT NULL DEFAULT '',
+			test             BOOLEAN NOT NULL DEFAULT false,
+			attempts         INTEGER NOT NULL DEFAULT 0,
+			response_code    INTEGER NOT NULL DEFAULT 0,
+			response_body    TEXT NOT NULL DEFAULT '',
+			response_hash    TEXT NOT NULL DEFAULT '',
+			response_truncated BOOLEAN NOT NULL DEFAULT false,
+			attempt_history  JSONB NOT NULL DEFAULT '[]',
+			confirm_token    TEXT NOT NULL DEFAULT '',
+			confirm_by       TIMESTAMP WITH TIME ZONE NULL,
+			confirmed_at     TIMESTAMP WITH TIME ZONE NULL,
+			error            TEXT NOT NULL DEFAULT '',
+			error_class      TEXT NOT NULL DEFAULT '',
+			next_retry_at    TIMESTAMP WITH TIME ZONE NULL DEFAULT NOW(),
+			requeued_at      TIMESTAMP WITH TIME ZONE NULL,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		-- Columns added after the first form of the table, for databases that already have it.
+		-- Existing logs get their idempotency keys as their message IDs, which are unique.
+		DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'webhook_logs' AND column_name = 'message_id') THEN
+				ALTER TABLE webhook_logs ADD COLUMN message_id uuid NULL;
+				UPDATE webhook_logs SET message_id = idempotency_key;
+				ALTER TABLE webhook_logs ALTER COLUMN message_id SET NOT NULL, ADD UNIQUE (message_id);
+			END IF;
+		END $$;
+		ALTER TABLE webhook_logs ADD COLUMN IF NOT EXISTS payload_gz BYTEA NULL;
+		ALTER TABLE webhook_logs ADD COLUMN IF NOT EXISTS payload_version TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhook_logs ADD COLUMN IF NOT EXISTS url TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhook_logs ADD COLUMN IF NOT EXISTS test BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE webhook_logs ADD COLUMN IF NOT EXISTS response_hash TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhook_logs ADD COLUMN IF NOT EXISTS response_truncated BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE webhook_logs ADD COLUMN IF NOT EXISTS attempt_history JSONB NOT NULL DEFAULT '[]';
+		ALTER TABLE webhook_logs ADD COLUMN IF NOT EXISTS confirm_token TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhook_logs ADD COLUMN IF NOT EXISTS confirm_by TIMESTAMP WITH TIME ZONE NULL;
+		ALTER TABLE webhook_logs ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMP WITH TIME ZONE NULL;
+		ALTER TABLE webhook_logs ADD COLUMN IF NOT EXISTS error_class TEXT NOT NULL DEFAULT '';
+		ALTER TABLE webhook_logs ADD COLUMN IF NOT EXISTS requeued_at TIMESTAMP WITH TIME ZONE NULL;
+
+		CREATE INDEX IF NOT EXISTS idx_webhook_logs_webhook_id ON webhook_logs(webhook_id);
+		CREATE INDEX IF NOT EXISTS idx_webhook_logs_sequence ON webhook_logs(webhook_id, sequence);
+		CREATE INDEX IF NOT EXISTS idx_webhook_logs_event ON webhook_logs(event);
+		CREATE INDEX IF NOT EXISTS idx_webhook_logs_pending ON webhook_logs(status, next_retry_at);
+		CREATE INDEX IF NOT EXISTS idx_webhook_logs_date ON webhook_logs((TIMEZONE('UTC', created_at)::DATE));
+
+		CREATE TABLE IF NOT EXISTS webhook_audit (
+			id               BIGSERIAL PRIMARY KEY,
+			webhook_id       INTEGER NOT NULL,
+			action           TEXT NOT NULL,
+			changes          JSONB NOT NULL DEFAULT '{}',
+			user_id          INTEGER NULL REFERENCES users(id) ON DELETE SET NULL ON UPDATE CASCADE,
+			user_name        TEXT NOT NULL DEFAULT '',
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_webhook_audit_webhook_id ON webhook_audit(webhook_id);
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Statuses added after the first form of the webhook types.
+	_, err = db.Exec(`
+		ALTER TYPE webhook_status ADD VALUE IF NOT EXISTS 'paused';
+		ALTER TYPE webhook_log_status ADD VALUE IF NOT EXISTS 'expired';
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Grant the webhook permissions to the Super Admin role.
+	_, err = db.Exec(`
+		UPDATE roles SET permissions = permissions || '{webhooks:get}' WHERE id = 1 AND NOT permissions @> '{webhooks:get}';
+		UPDATE roles SET permissions = permissions || '{webhooks:manage}' WHERE id = 1 AND NOT permissions @> '{webhooks:manage}';
+	`)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
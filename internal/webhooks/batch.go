@@ -0,0 +1,75 @@
+package webhooks
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+)
+
+// queueBatched queues a delivery log like queue, due after the given delay, but buffers
+// it for a batch insert if batching is enabled (Opt.InsertBatchSize). The buffer is
+// inserted once it's full or InsertBatchWait after its first log, whichever is earlier.
+func (m *Manager) queueBatched(webhookID int, ev models.WebhookEvent, delay time.Duration) error {
+	r, err := m.makeLogRow(webhookID, ev)
+	if err != nil {
+		return err
+	}
+	r.Delay = delay
+
+	if m.opt.InsertBatchSize < 2 {
+		_, err := m.insertLog(r)
+		return err
+	}
+
+	m.batchMu.Lock()
+	defer m.batchMu.Unlock()
+
+	m.batch = append(m.batch, r)
+	if len(m.batch) >= m.opt.InsertBatchSize {
+		return m.flushLogsLocked()
+	}
+
+	if m.batchTimer == nil {
+		m.batchTimer = time.AfterFunc(m.opt.InsertBatchWait, func() {
+			if err := m.flushLogs(); err != nil {
+				m.log.Println(err)
+			}
+		})
+	}
+
+	return nil
+}
+
+// flushLogs inserts the buffered delivery logs.
+func (m *Manager) flushLogs() error {
+	m.batchMu.Lock()
+	defer m.batchMu.Unlock()
+
+	return m.flushLogsLocked()
+}
+
+// flushLogsLocked inserts the buffered delivery logs in one go, which assigns them
+// the next numbers in their webhooks' log sequences in the order they were buffered.
+// It should be called with the batch lock held.
+func (m *Manager) flushLogsLocked() error {
+	if m.batchTimer != nil {
+		m.batchTimer.Stop()
+		m.batchTimer = nil
+	}
+	if len(m.batch) == 0 {
+		return nil
+	}
+
+	// The buffer is dropped even if the insert fails so that a bad log doesn't
+	// block the ones that come after it.
+	rows := slices.Clone(m.batch)
+	m.batch = m.batch[:0]
+
+	if err := m.store.CreateLogs(rows); err != nil {
+		return fmt.Errorf("error queueing %d webhook logs: %v", len(rows), err)
+	}
+
+	return nil
+}
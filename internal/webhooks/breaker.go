@@ -0,0 +1,175 @@
+package webhooks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+// breakers tracks the delivery circuit breakers of webhooks in memory, by webhook ID.
+// A webhook's circuit opens after threshold receiver failures within the window,
+// after which its deliveries are deferred until the cooldown passes. A single probe
+// delivery is then let through (half-open), which closes the circuit if it succeeds
+// or opens it again if it fails.
+type breakers struct {
+	sync.Mutex
+	hooks map[int]*breaker
+
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+
+	// Delay of the deliveries that are deferred while a probe is in flight.
+	probeWait time.Duration
+}
+
+// breaker is the circuit breaker of a single webhook.
+type breaker struct {
+	state string
+
+	// Times of the recent failures while the circuit is closed.
+	failures []time.Time
+
+	openedAt time.Time
+	probeAt  time.Time
+}
+
+// allow returns whether a delivery to a webhook can be attempted now, and if it can't,
+// the time until which the delivery should be deferred. probe is true if the delivery
+// is the probe of a half-open circuit.
+func (b *breakers) allow(webhookID int, now time.Time) (ok bool, until time.Time, probe bool) {
+	if b.threshold <= 0 {
+		return true, time.Time{}, false
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	br, ok := b.hooks[webhookID]
+	if !ok {
+		return true, time.Time{}, false
+	}
+
+	switch br.state {
+	case models.WebhookBreakerOpen:
+		if t := br.openedAt.Add(b.cooldown); now.Before(t) {
+			return false, t, false
+		}
+
+	case models.WebhookBreakerHalfOpen:
+		// Let another probe through if the previous one never reported back,
+		// eg: it didn't reach the receiver.
+		if now.Before(br.probeAt.Add(b.cooldown)) {
+			return false, now.Add(b.probeWait), false
+		}
+
+	default:
+		return true, time.Time{}, false
+	}
+
+	br.state, br.probeAt = models.WebhookBreakerHalfOpen, now
+	return true, time.Time{}, true
+}
+
+// reset closes the circuit of a webhook and clears its failures, eg: when its receiver
+// responds. It returns true if the circuit was open or half-open.
+func (b *breakers) reset(webhookID int) bool {
+	if b.threshold <= 0 {
+		return false
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	br, ok := b.hooks[webhookID]
+	if !ok {
+		return false
+	}
+	delete(b.hooks, webhookID)
+
+	return br.state != models.WebhookBreakerClosed
+}
+
+// failure counts a receiver failure of a webhook. It returns true if the failure
+// opened the circuit, ie: the threshold was reached or a probe failed.
+func (b *breakers) failure(webhookID int, now time.Time) bool {
+	if b.threshold <= 0 {
+		return false
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.hooks == nil {
+		b.hooks = make(map[int]*breaker)
+	}
+
+	br, ok := b.hooks[webhookID]
+	if !ok {
+		br = &breaker{state: models.WebhookBreakerClosed}
+		b.hooks[webhookID] = br
+	}
+
+	switch br.state {
+	case models.WebhookBreakerHalfOpen:
+		br.state, br.openedAt = models.WebhookBreakerOpen, now
+		return true
+
+	// Deliveries that were already in flight when the circuit opened.
+	case models.WebhookBreakerOpen:
+		return false
+	}
+
+	// Drop the failures that have fallen out of the window.
+	if b.window > 0 {
+		n := 0
+		for _, t := range br.failures {
+			if now.Sub(t) < b.window {
+				br.failures[n] = t
+				n++
+			}
+		}
+		br.failures = br.failures[:n]
+	}
+
+	br.failures = append(br.failures, now)
+	if len(br.failures) < b.threshold {
+		return false
+	}
+
+	br.state, br.openedAt, br.failures = models.WebhookBreakerOpen, now, nil
+	return true
+}
+
+// get returns the state of a webhook's circuit breaker.
+func (b *breakers) get(webhookID int) models.WebhookBreaker {
+	out := models.WebhookBreaker{WebhookID: webhookID, State: models.WebhookBreakerClosed}
+	if b.threshold <= 0 {
+		return out
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	br, ok := b.hooks[webhookID]
+	if !ok {
+		return out
+	}
+
+	out.State = br.state
+	for _, t := range br.failures {
+		if b.window <= 0 || time.Since(t) < b.window {
+			out.Failures++
+		}
+	}
+	if br.state != models.WebhookBreakerClosed {
+		out.OpenedAt = null.TimeFrom(br.openedAt)
+	}
+	if br.state == models.WebhookBreakerOpen {
+		out.RetryAt = null.TimeFrom(br.openedAt.Add(b.cooldown))
+	}
+
+	return out
+}
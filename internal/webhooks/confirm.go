@@ -0,0 +1,54 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+)
+
+// confirmWindow returns the window within which a webhook's receiver has to confirm
+// a delivery, or 0 if deliveries don't have to be confirmed.
+func confirmWindow(w models.Webhook) time.Duration {
+	d, err := time.ParseDuration(w.ConfirmWindow)
+	if err != nil || d <= 0 {
+		return 0
+	}
+
+	return d
+}
+
+// addConfirmURL adds the URL at which the receiver confirms a delivery to the event
+// envelope as confirm_url, eg: /webhooks/confirm/{message_id}?token={token}. The URL
+// has a random token that's generated on the log's first delivery. It's stored before
+// every delivery so that the receiver can confirm it while the request is in flight.
+func (m *Manager) addConfirmURL(l *PendingLog, payload []byte) ([]byte, error) {
+	if l.ConfirmToken == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("error generating confirmation token: %v", err)
+		}
+		l.ConfirmToken = hex.EncodeToString(b)
+	}
+
+	if err := m.store.SetLogConfirmToken(l.ID, l.ConfirmToken); err != nil {
+		return nil, fmt.Errorf("error storing confirmation token: %v", err)
+	}
+
+	var env map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return nil, fmt.Errorf("error decoding payload: %v", err)
+	}
+
+	u, err := json.Marshal(m.opt.ConfirmURL + "/" + url.PathEscape(l.MessageID) + "?token=" + l.ConfirmToken)
+	if err != nil {
+		return nil, err
+	}
+	env["confirm_url"] = u
+
+	return json.Marshal(env)
+}
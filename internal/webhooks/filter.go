@@ -0,0 +1,113 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// ValidateFilterExpression checks whether a webhook's filter expression compiles.
+func ValidateFilterExpression(s string) error {
+	_, err := compileFilter(s)
+	return err
+}
+
+// compileFilter compiles a filter expression that's evaluated against the fields
+// of an event's data, eg: `subscriber.status == "blocklisted"`, to a bool.
+func compileFilter(s string) (*vm.Program, error) {
+	return expr.Compile(s, expr.AsBool())
+}
+
+// matchFilter checks whether an event's data (as a JSON object) matches a webhook's
+// filter expression. Compiled expressions are cached by their source.
+func (m *Manager) matchFilter(filter string, data map[string]any) (bool, error) {
+	m.filtersMu.Lock()
+	p, ok := m.filters[filter]
+	if !ok {
+		var err error
+		if p, err = compileFilter(filter); err != nil {
+			m.filtersMu.Unlock()
+			return false, err
+		}
+		m.filters[filter] = p
+	}
+	m.filtersMu.Unlock()
+
+	out, err := expr.Run(p, data)
+	if err != nil {
+		return false, err
+	}
+
+	ok, _ = out.(bool)
+	return ok, nil
+}
+
+// filterEnv returns the environment that filter expressions are evaluated against:
+// the fields of the event's data if it's a JSON object.
+func filterEnv(b []byte) (map[string]any, error) {
+	out := map[string]any{}
+	if isEmptyData(b) {
+		return out, nil
+	}
+
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("error decoding event data: %v", err)
+	}
+	if m, ok := v.(map[string]any); ok {
+		out = m
+	}
+
+	return out, nil
+}
+
+// eventListIDs returns the IDs of the lists that an event's data (as a JSON object)
+// is about: the list_ids of subscription events, the list of list events, and the
+// lists of subscribers (before and after an update) and campaigns.
+func eventListIDs(data map[string]any) []int64 {
+	var out []int64
+	add := func(v any) {
+		if id, ok := v.(float64); ok {
+			out = append(out, int64(id))
+		}
+	}
+	addLists := func(v any) {
+		items, _ := v.([]any)
+		for _, it := range items {
+			if l, ok := it.(map[string]any); ok {
+				add(l["id"])
+			}
+		}
+	}
+
+	if ids, ok := data["list_ids"].([]any); ok {
+		for _, id := range ids {
+			add(id)
+		}
+	}
+	if l, ok := data["list"].(map[string]any); ok {
+		add(l["id"])
+	}
+	addLists(data["lists"])
+	for _, k := range []string{"subscriber", "previous", "campaign"} {
+		if o, ok := data[k].(map[string]any); ok {
+			addLists(o["lists"])
+		}
+	}
+
+	return out
+}
+
+// hasAnyListID checks whether any of the IDs is in a webhook's list scope.
+func hasAnyListID(scope []int64, ids []int64) bool {
+	for _, id := range ids {
+		if slices.Contains(scope, id) {
+			return true
+		}
+	}
+
+	return false
+}
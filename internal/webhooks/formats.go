@@ -0,0 +1,382 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+)
+
+// slackMessage is a message for Slack incoming webhooks. text is the fallback for
+// notifications and blocks are the rich message.
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// formatEvent is an event envelope that's decoded for formatting.
+type formatEvent struct {
+	Event     string              `json:"event"`
+	Timestamp time.Time           `json:"timestamp"`
+	Actor     models.WebhookActor `json:"actor"`
+	Data      json.RawMessage     `json:"data"`
+	Test      bool                `json:"test"`
+}
+
+// discordMessage is a message for Discord webhooks with an embed of the event.
+type discordMessage struct {
+	Embeds          []discordEmbed         `json:"embeds"`
+	AllowedMentions discordAllowedMentions `json:"allowed_mentions"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Color       int            `json:"color"`
+	Timestamp   string         `json:"timestamp"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+// discordAllowedMentions with no parse types stops @everyone, @here, and other
+// mentions in event data from pinging anyone.
+type discordAllowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// eventData is the data of an event that's decoded for formatting.
+type eventData map[string]any
+
+// markup is the markup language of a message format in which event summaries are written.
+type markup struct {
+	escape func(string) string
+	bold   func(string) string
+}
+
+var (
+	slackMarkup = markup{
+		escape: escapeSlack,
+		bold:   func(s string) string { return "*" + s + "*" },
+	}
+
+	discordMarkup = markup{
+		escape: escapeDiscord,
+		bold:   func(s string) string { return "**" + s + "**" },
+	}
+)
+
+const (
+	// Discord rejects messages with longer contents, and while embed descriptions
+	// can be longer, the same limit is kept for them.
+	discordMaxDescription = 2000
+
+	// Colors of Discord embeds by the kind of event.
+	discordColorDefault    = 0x0055d4
+	discordColorSubscriber = 0x0fa36b
+	discordColorCampaign   = 0x7f2aff
+	discordColorWarning    = 0xe6a700
+	discordColorNegative   = 0xd62f2f
+	discordColorTest       = 0x99a2ad
+)
+
+// discordColors map events to the colors of their Discord embeds. Events that aren't
+// here are colored by their prefix.
+var discordColors = map[string]int{
+	models.EventSubscriberDeleted:      discordColorNegative,
+	models.EventSubscriberUnsubscribed: discordColorWarning,
+	models.EventSubscriberBounced:      discordColorWarning,
+	models.EventSubscriberBlocklisted:  discordColorNegative,
+	models.EventCampaignPaused:         discordColorWarning,
+	models.EventCampaignCancelled:      discordColorNegative,
+	models.EventListDeleted:            discordColorNegative,
+	models.EventWebhookTest:            discordColorTest,
+}
+
+// eventSummaries map events to the summaries of their messages in a format's markup.
+// Events that aren't here get a generic summary.
+var eventSummaries = map[string]func(d eventData, mk markup) string{
+	models.EventSubscriberCreated: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Subscriber %s was created", d.subscriber(mk))
+	},
+	models.EventSubscriberUpdated: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Subscriber %s was updated", d.subscriber(mk))
+	},
+	models.EventSubscriberDeleted: func(d eventData, mk markup) string {
+		return fmt.Sprintf("%d subscriber(s) were deleted", d.count("subscriber_ids"))
+	},
+	models.EventSubscriberAddedToList: func(d eventData, mk markup) string {
+		return fmt.Sprintf("%d subscriber(s) were added to %d list(s)", d.count("subscriber_ids"), d.count("list_ids"))
+	},
+	models.EventSubscriberRemovedFromList: func(d eventData, mk markup) string {
+		return fmt.Sprintf("%d subscriber(s) were removed from %d list(s)", d.count("subscriber_ids"), d.count("list_ids"))
+	},
+	models.EventSubscriberUnsubscribed: func(d eventData, mk markup) string {
+		// Unsubscriptions from a campaign have the subscriber, and the others, their IDs.
+		if _, ok := d["subscriber"]; ok {
+			return fmt.Sprintf("Subscriber %s unsubscribed", d.subscriber(mk))
+		}
+		return fmt.Sprintf("%d subscriber(s) were unsubscribed from %d list(s)", d.count("subscriber_ids"), d.count("list_ids"))
+	},
+	models.EventSubscriberBounced: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Subscriber %s bounced (%s)", d.subscriber(mk), mk.escape(strings.Join(d.strs("type", "source", "diagnostic_code"), ", ")))
+	},
+	models.EventSubscriberReactivated: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Subscriber %s was reactivated", d.subscriber(mk))
+	},
+	models.EventSubscriberDataRequested: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Subscriber %s requested an export of their data", d.subscriber(mk))
+	},
+	models.EventSubscriberBlocklisted: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Subscriber %s was blocklisted (%s)", d.subscriber(mk), mk.escape(d.str("reason")))
+	},
+	models.EventCampaignCreated: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Campaign %s was created", d.campaign(mk))
+	},
+	models.EventCampaignUpdated: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Campaign %s was updated", d.campaign(mk))
+	},
+	models.EventCampaignStarted: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Campaign %s started sending", d.campaign(mk))
+	},
+	models.EventCampaignProgress: func(d eventData, mk markup) string {
+		m, _ := d["progress"].(map[string]any)
+		p := eventData(m)
+		return fmt.Sprintf("Campaign %s is %s%% sent\nSent %s of %s · %s/min",
+			d.campaign(mk), p.str("percent"), p.str("sent"), p.str("to_send"), p.str("rate"))
+	},
+	models.EventCampaignPaused: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Campaign %s was paused", d.campaign(mk))
+	},
+	models.EventCampaignCancelled: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Campaign %s was cancelled", d.campaign(mk))
+	},
+	models.EventCampaignFinished: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Campaign %s finished sending", d.campaign(mk)) + d.campaignStats()
+	},
+	models.EventCampaignArchived: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Campaign %s was published to the archive", d.campaign(mk)) + d.campaignStats()
+	},
+	models.EventListCreated: func(d eventData, mk markup) string {
+		return fmt.Sprintf("List %s was created", mk.bold(mk.escape(d.get("list", "name"))))
+	},
+	models.EventListUpdated: func(d eventData, mk markup) string {
+		return fmt.Sprintf("List %s was updated", mk.bold(mk.escape(d.get("list", "name"))))
+	},
+	models.EventListDeleted: func(d eventData, mk markup) string {
+		return fmt.Sprintf("List %s was deleted", mk.bold(mk.escape(d.get("list", "name"))))
+	},
+	models.EventTemplateUpdated: func(d eventData, mk markup) string {
+		return fmt.Sprintf("Template %s was updated", mk.bold(mk.escape(d.get("template", "name"))))
+	},
+	models.EventWebhookTest: func(d eventData, mk markup) string {
+		return "Test event from listmonk"
+	},
+}
+
+// formatSlack formats an event envelope as a Slack message with a summary of the
+// event and a context line with the event, its actor, and its time.
+func formatSlack(payload []byte) ([]byte, error) {
+	var ev formatEvent
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return nil, fmt.Errorf("error decoding payload for slack: %v", err)
+	}
+
+	// Data that isn't an object, eg: null, is formatted as empty.
+	var d eventData
+	_ = json.Unmarshal(ev.Data, &d)
+
+	text := fmt.Sprintf("listmonk event `%s`", escapeSlack(ev.Event))
+	if fn, ok := eventSummaries[ev.Event]; ok {
+		text = fn(d, slackMarkup)
+	}
+	if ev.Test {
+		text = "[test] " + text
+	}
+
+	ctx := []string{"`" + escapeSlack(ev.Event) + "`"}
+	if a := actorName(ev.Actor); a != "" {
+		ctx = append(ctx, escapeSlack(a))
+	}
+	ctx = append(ctx, fmt.Sprintf("<!date^%d^{date_short_pretty} {time}|%s>", ev.Timestamp.Unix(), ev.Timestamp.UTC().Format(time.RFC3339)))
+
+	return json.Marshal(slackMessage{
+		Text: text,
+		Blocks: []slackBlock{
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}},
+			{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: strings.Join(ctx, " · ")}}},
+		},
+	})
+}
+
+// formatDiscord formats an event envelope as a Discord message with an embed that has
+// the event as its title, its summary as its description, and its actor in the footer.
+func formatDiscord(payload []byte) ([]byte, error) {
+	var ev formatEvent
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return nil, fmt.Errorf("error decoding payload for discord: %v", err)
+	}
+
+	// Data that isn't an object, eg: null, is formatted as empty.
+	var d eventData
+	_ = json.Unmarshal(ev.Data, &d)
+
+	desc := fmt.Sprintf("listmonk event `%s`", ev.Event)
+	if fn, ok := eventSummaries[ev.Event]; ok {
+		desc = fn(d, discordMarkup)
+	}
+	if ev.Test {
+		desc = "[test] " + desc
+	}
+
+	// Truncate long descriptions, eg: of subscribers with long e-mails, by runes
+	// so that multi-byte characters aren't split.
+	if r := []rune(desc); len(r) > discordMaxDescription {
+		desc = string(r[:discordMaxDescription-1]) + "…"
+	}
+
+	color, ok := discordColors[ev.Event]
+	if !ok {
+		switch {
+		case strings.HasPrefix(ev.Event, "subscriber."):
+			color = discordColorSubscriber
+		case strings.HasPrefix(ev.Event, "campaign."):
+			color = discordColorCampaign
+		default:
+			color = discordColorDefault
+		}
+	}
+
+	e := discordEmbed{
+		Title:       ev.Event,
+		Description: desc,
+		Color:       color,
+		Timestamp:   ev.Timestamp.UTC().Format(time.RFC3339),
+	}
+	if a := actorName(ev.Actor); a != "" {
+		e.Footer = &discordFooter{Text: a}
+	}
+
+	return json.Marshal(discordMessage{
+		Embeds:          []discordEmbed{e},
+		AllowedMentions: discordAllowedMentions{Parse: []string{}},
+	})
+}
+
+// actorName returns a readable name of an event's actor.
+func actorName(a models.WebhookActor) string {
+	switch {
+	case a.Name != "":
+		return a.Type + " " + a.Name
+	case a.UUID != "":
+		return a.Type + " " + a.UUID
+	}
+
+	return a.Type
+}
+
+// get returns a nested string field of the data, eg: get("campaign", "name").
+func (d eventData) get(key, field string) string {
+	m, ok := d[key].(map[string]any)
+	if !ok {
+		return ""
+	}
+
+	return eventData(m).str(field)
+}
+
+// str returns a field of the data as a string.
+func (d eventData) str(key string) string {
+	switch v := d[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// strs returns the non-empty fields of the data as strings.
+func (d eventData) strs(keys ...string) []string {
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if s := d.str(k); s != "" {
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
+
+// count returns the number of items in a list field of the data.
+func (d eventData) count(key string) int {
+	v, _ := d[key].([]any)
+	return len(v)
+}
+
+// subscriber returns the e-mail, or the UUID, of the data's subscriber.
+func (d eventData) subscriber(mk markup) string {
+	s := d.get("subscriber", "email")
+	if s == "" {
+		s = d.get("subscriber", "uuid")
+	}
+
+	return mk.escape(s)
+}
+
+// campaign returns the name of the data's campaign in bold.
+func (d eventData) campaign(mk markup) string {
+	s := d.get("campaign", "name")
+	if s == "" {
+		s = d.get("campaign", "uuid")
+	}
+
+	return mk.bold(mk.escape(s))
+}
+
+// campaignStats returns a line with the data's campaign stats, if there are any.
+// The views, clicks, and bounces are only shown if they're in the stats.
+func (d eventData) campaignStats() string {
+	m, ok := d["stats"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	st := eventData(m)
+
+	s := fmt.Sprintf("\nSent %s of %s", st.str("sent"), st.str("to_send"))
+	for _, k := range []string{"views", "clicks", "bounces"} {
+		if v := st.str(k); v != "" {
+			s += fmt.Sprintf(" · %s %s", v, k)
+		}
+	}
+
+	return s
+}
+
+// escapeSlack escapes the control characters of Slack's mrkdwn.
+func escapeSlack(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// escapeDiscord escapes the control characters of Discord's markdown.
+func escapeDiscord(s string) string {
+	return strings.NewReplacer(
+		"\\", "\\\\", "*", "\\*", "_", "\\_", "~", "\\~", "`", "\\`", "|", "\\|", ">", "\\>", "#", "\\#",
+	).Replace(s)
+}
@@ -0,0 +1,176 @@
+package webhooks
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Upper bounds (seconds) of the buckets of the delivery latency histogram.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics is a snapshot of the delivery metrics of webhooks since the manager was started.
+type Metrics struct {
+	Webhooks []WebhookMetrics `json:"webhooks"`
+}
+
+// WebhookMetrics are the delivery metrics of a single webhook.
+type WebhookMetrics struct {
+	WebhookID int `json:"webhook_id"`
+
+	// Delivery attempts, including retries, and their outcomes.
+	Attempts  int64 `json:"attempts"`
+	Successes int64 `json:"successes"`
+	Failures  int64 `json:"failures"`
+	Retries   int64 `json:"retries"`
+
+	// Latency of the requests that got a response.
+	Latency Histogram `json:"latency"`
+}
+
+// Histogram is a cumulative histogram of durations in seconds.
+type Histogram struct {
+	Buckets []Bucket `json:"buckets"`
+	Sum     float64  `json:"sum"`
+	Count   int64    `json:"count"`
+}
+
+// Bucket is the number of observations that are <= LE seconds.
+type Bucket struct {
+	LE    float64 `json:"le"`
+	Count int64   `json:"count"`
+}
+
+// metrics tracks the delivery metrics of webhooks in memory.
+type metrics struct {
+	sync.Mutex
+	hooks map[int]*WebhookMetrics
+}
+
+// get returns the metrics of a webhook, creating them if they don't exist. It
+// should be called with the lock held.
+func (m *metrics) get(webhookID int) *WebhookMetrics {
+	if m.hooks == nil {
+		m.hooks = make(map[int]*WebhookMetrics)
+	}
+
+	w, ok := m.hooks[webhookID]
+	if !ok {
+		w = &WebhookMetrics{WebhookID: webhookID, Latency: Histogram{Buckets: make([]Bucket, len(latencyBuckets))}}
+		for i, le := range latencyBuckets {
+			w.Latency.Buckets[i].LE = le
+		}
+		m.hooks[webhookID] = w
+	}
+
+	return w
+}
+
+// attempt counts a delivery attempt. Attempts after the first are retries.
+func (m *metrics) attempt(webhookID, attempt int) {
+	m.Lock()
+	defer m.Unlock()
+
+	w := m.get(webhookID)
+	w.Attempts++
+	if attempt > 1 {
+		w.Retries++
+	}
+}
+
+// latency records the duration of a request in the latency histogram.
+func (m *metrics) latency(webhookID int, d time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+
+	var (
+		h = &m.get(webhookID).Latency
+		s = d.Seconds()
+	)
+	for i := range h.Buckets {
+		if s <= h.Buckets[i].LE {
+			h.Buckets[i].Count++
+		}
+	}
+	h.Sum += s
+	h.Count++
+}
+
+// outcome counts the success or failure of a delivery attempt.
+func (m *metrics) outcome(webhookID int, ok bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	w := m.get(webhookID)
+	if ok {
+		w.Successes++
+	} else {
+		w.Failures++
+	}
+}
+
+// snapshot returns a copy of the metrics ordered by webhook ID.
+func (m *metrics) snapshot() Metrics {
+	m.Lock()
+	defer m.Unlock()
+
+	out := Metrics{Webhooks: make([]WebhookMetrics, 0, len(m.hooks))}
+	for _, w := range m.hooks {
+		c := *w
+		c.Latency.Buckets = slices.Clone(w.Latency.Buckets)
+		out.Webhooks = append(out.Webhooks, c)
+	}
+	slices.SortFunc(out.Webhooks, func(a, b WebhookMetrics) int {
+		return a.WebhookID - b.WebhookID
+	})
+
+	return out
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (m Metrics) WritePrometheus(w io.Writer) error {
+	counters := []struct {
+		name, help string
+		val        func(WebhookMetrics) int64
+	}{
+		{"listmonk_webhook_delivery_attempts_total", "Webhook delivery attempts, including retries.", func(m WebhookMetrics) int64 { return m.Attempts }},
+		{"listmonk_webhook_delivery_successes_total", "Successful webhook delivery attempts.", func(m WebhookMetrics) int64 { return m.Successes }},
+		{"listmonk_webhook_delivery_failures_total", "Failed webhook delivery attempts.", func(m WebhookMetrics) int64 { return m.Failures }},
+		{"listmonk_webhook_delivery_retries_total", "Webhook delivery attempts that were retries.", func(m WebhookMetrics) int64 { return m.Retries }},
+	}
+
+	for _, c := range counters {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name); err != nil {
+			return err
+		}
+		for _, h := range m.Webhooks {
+			if _, err := fmt.Fprintf(w, "%s{webhook_id=\"%d\"} %d\n", c.name, h.WebhookID, c.val(h)); err != nil {
+				return err
+			}
+		}
+	}
+
+	const name = "listmonk_webhook_delivery_duration_seconds"
+	if _, err := fmt.Fprintf(w, "# HELP %s Latency of webhook delivery requests.\n# TYPE %s histogram\n", name, name); err != nil {
+		return err
+	}
+	for _, h := range m.Webhooks {
+		for _, b := range h.Latency.Buckets {
+			le := strconv.FormatFloat(b.LE, 'f', -1, 64)
+			if _, err := fmt.Fprintf(w, "%s_bucket{webhook_id=\"%d\",le=\"%s\"} %d\n", name, h.WebhookID, le, b.Count); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket{webhook_id=\"%d\",le=\"+Inf\"} %d\n%s_sum{webhook_id=\"%d\"} %s\n%s_count{webhook_id=\"%d\"} %d\n",
+			name, h.WebhookID, h.Latency.Count,
+			name, h.WebhookID, strconv.FormatFloat(h.Latency.Sum, 'f', -1, 64),
+			name, h.WebhookID, h.Latency.Count); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrBlockedAddr is returned when a webhook's receiver only resolves to IPs
+// in the blocked ranges (Opt.BlockedCIDRs), eg: a cloud metadata endpoint.
+var ErrBlockedAddr = errors.New("receiver address is blocked")
+
+// dialContext dials a receiver after checking that its IPs aren't in the blocked ranges,
+// unless its host is one of the allowed hosts. The connection is made to a checked IP
+// instead of the host so that the host can't be re-resolved to a blocked IP in between,
+// ie: DNS rebinding.
+func (m *Manager) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &net.Dialer{Timeout: time.Second * 30, KeepAlive: time.Second * 30}
+	if len(m.opt.BlockedCIDRs) == 0 {
+		return d.DialContext(ctx, network, addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if m.isAllowedHost(host, port) {
+		return d.DialContext(ctx, network, addr)
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	// Try the IPs that aren't blocked.
+	var lastErr error
+	for _, ip := range ips {
+		if n := m.blockedNet(ip.IP); n != nil {
+			lastErr = fmt.Errorf("%w: %s resolves to %s in %s", ErrBlockedAddr, host, ip.IP, n)
+			continue
+		}
+
+		conn, err := d.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no addresses found for %s", host)
+	}
+
+	return nil, lastErr
+}
+
+// CheckURL checks whether a receiver URL's host only resolves to IPs in the blocked ranges,
+// in which case ErrBlockedAddr is returned, for failing early, eg: on test deliveries.
+// Deliveries are checked when they're made irrespective of this.
+func (m *Manager) CheckURL(ctx context.Context, u *url.URL) error {
+	if len(m.opt.BlockedCIDRs) == 0 {
+		return nil
+	}
+
+	host, port, err := net.SplitHostPort(URLHostPort(u))
+	if err != nil {
+		return err
+	}
+	if m.isAllowedHost(host, port) {
+		return nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	for _, ip := range ips {
+		if m.blockedNet(ip.IP) == nil {
+			return nil
+		}
+	}
+	if len(ips) == 0 {
+		return fmt.Errorf("no addresses found for %s", host)
+	}
+
+	return fmt.Errorf("%w: %s resolves to %s in %s", ErrBlockedAddr, host, ips[0].IP, m.blockedNet(ips[0].IP))
+}
+
+// isAllowedHost checks whether a host is in the allowed hosts, which can be a host,
+// eg: hooks.internal, or a host:port.
+func (m *Manager) isAllowedHost(host, port string) bool {
+	var (
+		h  = strings.ToLower(strings.Trim(host, "[]"))
+		hp = net.JoinHostPort(h, port)
+	)
+	for _, a := range m.opt.AllowedHosts {
+		if a == h || a == hp {
+			return true
+		}
+	}
+
+	return false
+}
+
+// blockedNet returns the blocked range that an IP is in, if any.
+func (m *Manager) blockedNet(ip net.IP) *net.IPNet {
+	for _, n := range m.opt.BlockedCIDRs {
+		if n.Contains(ip) {
+			return n
+		}
+	}
+
+	return nil
+}
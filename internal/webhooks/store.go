@@ -0,0 +1,101 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"go.opentelemetry.io/otel/trace"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+// Store is the persistence backend of the webhook manager, in which the delivery logs
+// (the queue of pending deliveries and their outcomes) and the delivery state of the
+// webhooks are stored. The default implementation is over the primary (Postgres) DB,
+// which is also the source of truth for the webhooks themselves, but an alternative
+// backend, eg: for the pending queue, can be plugged in for high throughput.
+type Store interface {
+	// GetWebhooksByEvent returns the enabled webhooks that are subscribed to an event,
+	// with the highest weight first.
+	GetWebhooksByEvent(event string) ([]models.Webhook, error)
+
+	// GetWebhook returns a webhook by its ID.
+	GetWebhook(id int) (models.Webhook, error)
+
+	// CreateLog inserts a pending delivery log and returns its ID. CreateLogs inserts
+	// several in one go, which are assigned their webhooks' log sequence numbers in order.
+	CreateLog(r LogRow) (int, error)
+	CreateLogs(rows []LogRow) error
+
+	// CreateFailedLog records a log that's failed without being delivered and returns its ID.
+	CreateFailedLog(r LogRow, errMsg, errClass string) (int, error)
+
+	// NextLogs leases and returns up to limit due pending logs of the given events (or all
+	// events if empty) except the excluded events, with their webhooks. A lease keeps the
+	// logs from being picked up again by other workers or instances for the lease duration.
+	NextLogs(limit int, lease time.Duration, events, exclude []string) ([]PendingLog, error)
+
+	// SetLogConfirmToken stores the token with which the receiver confirms a delivery.
+	SetLogConfirmToken(id int, token string) error
+
+	// UpdateLogSuccess records a successful delivery. Logs with a confirmBy time
+	// stay pending until they're confirmed or the time passes.
+	UpdateLogSuccess(l models.WebhookLog, attempts, code int, body string, confirmBy null.Time) error
+
+	// UpdateLogFailed records a failed delivery attempt, which is retried at next, if
+	// it's set, or is failed permanently (or expired, if expired is set) otherwise.
+	UpdateLogFailed(l models.WebhookLog, attempts, code int, body, errMsg, errClass string, next null.Time, expired bool) error
+
+	// AppendLogAttempt appends a failed attempt to a log's attempt history.
+	AppendLogAttempt(id int, a models.WebhookAttempt) error
+
+	// UpdateLogExpired expires a pending log without delivering it.
+	UpdateLogExpired(id int, msg string) error
+
+	// DeferLog reschedules a pending log to the given time without counting an attempt.
+	DeferLog(id int, until time.Time) error
+
+	// RecordFailure counts a permanently failed delivery towards a webhook's consecutive
+	// failures, disabling it at its failure threshold, and returns the count and threshold.
+	RecordFailure(webhookID int) (failures int, threshold int, err error)
+
+	// ResetFailures resets the consecutive failures of a webhook.
+	ResetFailures(webhookID int) error
+}
+
+// LogRow is a delivery log that's ready to be inserted.
+type LogRow struct {
+	WebhookID int
+	Event     string
+	Key       string
+	MessageID string
+	Payload   json.RawMessage
+	Test      bool
+
+	// Delay after which the log is due for delivery, eg: to spread out the
+	// deliveries of an event that's queued for many webhooks.
+	Delay time.Duration
+
+	// Gzip compressed payload, if the payload is stored compressed, in which
+	// case Payload is an empty object.
+	PayloadGZ []byte
+}
+
+// PendingLog is a pending delivery log that's leased for delivery.
+type PendingLog struct {
+	models.WebhookLog
+
+	// JSON of the log's webhook, as it was when the log was leased.
+	WebhookRaw json.RawMessage `db:"webhook"`
+	Webhook    models.Webhook  `db:"-"`
+
+	// Span of the delivery attempt, if it's being delivered.
+	span trace.Span
+
+	// Outcome of a synchronous delivery (DeliverNow), in which case the log
+	// isn't stored and the outcome is recorded here instead.
+	result *DeliveryResult
+
+	// Request of a preview (Preview), which is recorded here instead of being sent.
+	preview *Preview
+}
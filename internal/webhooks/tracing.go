@@ -0,0 +1,54 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts the span of a delivery attempt with the log's webhook, event, and
+// attempt. Spans are no-ops if there's no TracerProvider.
+func (m *Manager) startSpan(ctx context.Context, l PendingLog, attempts int, method string) (context.Context, trace.Span) {
+	return m.tracer.Start(ctx, "webhook.deliver",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.Int("listmonk.webhook.id", l.WebhookID),
+			attribute.String("listmonk.webhook.name", l.Webhook.Name),
+			attribute.String("listmonk.webhook.event", l.Event),
+			attribute.Int("listmonk.webhook.log_id", l.ID),
+			attribute.String("listmonk.webhook.message_id", l.MessageID),
+			attribute.Int("listmonk.webhook.attempt", attempts),
+			attribute.String("http.request.method", method),
+		))
+}
+
+// injectTrace adds the trace context of a delivery's span to its request, eg: traceparent,
+// so that the receiver can continue the trace. It's a no-op unless tracing is enabled.
+func injectTrace(ctx context.Context, req *http.Request) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+}
+
+// traceResponse records the response of a delivery attempt on its span.
+func traceResponse(span trace.Span, code int, latency time.Duration) {
+	span.SetAttributes(
+		attribute.Int("http.response.status_code", code),
+		attribute.Float64("listmonk.webhook.latency_ms", float64(latency.Microseconds())/1000),
+	)
+}
+
+// traceError records a failed delivery attempt on its span, if it has one.
+func traceError(span trace.Span, err error, retry bool) {
+	if span == nil {
+		return
+	}
+
+	span.SetAttributes(attribute.Bool("listmonk.webhook.retry", retry))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
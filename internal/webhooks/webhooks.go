@@ -0,0 +1,1939 @@
+// package webhooks queues and delivers outgoing webhook events. Events are
+// recorded as delivery logs in a Store (the DB by default) and are picked up
+// and posted by pools of workers. Failed deliveries are retried with their
+// webhook's backoff strategy (exponential by default, linear, or fixed),
+// jittered, and capped at a max delay between attempts.
+package webhooks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/expr-lang/expr/vm"
+	"github.com/gofrs/uuid/v5"
+	"github.com/knadh/listmonk/models"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/text/encoding/htmlindex"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+const (
+	// Max number of bytes of a receiver's response body that's recorded in the logs.
+	maxRespBodyLen = 1024
+
+	// Max number of bytes of a receiver's response body that's read for matching
+	// it against a webhook's success conditions.
+	maxMatchBodyLen = 1024 * 64
+
+	// Max value of a webhook's max_response_body.
+	MaxResponseBodyLen = 1024 * 1024
+
+	// Default base and max delays between retries of a failed delivery.
+	retryBackoff    = time.Second * 30
+	maxRetryBackoff = time.Hour * 2
+
+	defaultTimeout = time.Second * 10
+
+	// Replacement of the redacted parts of response bodies.
+	redactedText = "[redacted]"
+
+	// Default max time that logs are buffered for a batch insert.
+	defaultInsertBatchWait = time.Millisecond * 200
+
+	// Default time for which an open circuit defers a webhook's deliveries.
+	defaultBreakerCooldown = time.Minute
+)
+
+// Opt represents the webhook manager's options.
+type Opt struct {
+	// Number of concurrent delivery workers in the default pool, which delivers
+	// the events that aren't in any of the Pools.
+	Workers int
+
+	// Optional dedicated worker pools for sets of events, eg: critical events
+	// that shouldn't be delayed by a backlog of bulk events in the default pool.
+	Pools []Pool
+
+	// Number of pending logs a worker fetches and delivers in one go.
+	BatchSize int
+
+	// Interval at which workers poll the store for pending logs.
+	Interval time.Duration
+
+	// Stagger the first poll of every worker by a fraction of the Interval so
+	// that the workers' polls are spread over the interval instead of hitting
+	// the store in lockstep.
+	Stagger bool
+
+	// Duration for which a fetched log is leased to a worker before
+	// other workers can pick it up again.
+	LeaseDuration time.Duration
+
+	// Fraction of a retry's delay by which it's randomly moved back or forth, eg:
+	// 0.2 = ±20%, so that the retries of deliveries that failed together, eg: in an
+	// outage, are spread out instead of hitting the receiver at once. 0 disables it.
+	RetryJitter float64
+
+	// Optional source of randomness for the jitter, eg: a seeded source for
+	// deterministic delays. Defaults to a time seeded source.
+	RandSource rand.Source
+
+	// host:port (or host for any port) at which listmonk itself is reachable.
+	// URLs resolved from webhook URL templates can't point at these.
+	SelfHosts []string
+
+	// IP ranges that receivers can't be reached at, eg: loopback, link-local
+	// (cloud metadata endpoints), and private networks, to prevent webhooks from
+	// being used for SSRF. The hosts (host or host:port) in AllowedHosts are exempt.
+	BlockedCIDRs []*net.IPNet
+	AllowedHosts []string
+
+	// Patterns that are redacted from the recorded response bodies, eg: secrets that
+	// receivers echo back. Only the first group of a pattern is redacted, if it has one.
+	RedactPatterns []*regexp.Regexp
+
+	// Store the payloads of new logs gzip compressed (payload_gz) instead of as JSONB.
+	CompressPayloads bool
+
+	// Public URL at which receivers confirm deliveries of webhooks with a confirm_window,
+	// eg: https://listmonk.example.com/webhooks/confirm.
+	ConfirmURL string
+
+	// Buffer the logs of triggered events and insert up to InsertBatchSize of them at once,
+	// waiting up to InsertBatchWait for a batch to fill, eg: to cut down on inserts during
+	// imports. Batching is disabled if the size is < 2.
+	InsertBatchSize int
+	InsertBatchWait time.Duration
+
+	// Max delay over which the first deliveries of a custom event to its webhooks are
+	// staggered (and jittered by RetryJitter), so that triggering an event that many
+	// webhooks are subscribed to doesn't cause a burst of deliveries. 0 disables it.
+	TriggerSpread time.Duration
+
+	// Max number of webhooks that an event is queued for. The webhooks with the highest
+	// weights get the event and the rest are skipped. 0 is unlimited.
+	MaxPerEvent int
+
+	// Optional OpenTelemetry tracer provider with which every delivery attempt is
+	// recorded as a span. It's shut down, flushing its spans, on Close if it can be.
+	TracerProvider trace.TracerProvider
+
+	// Allow webhooks to skip TLS certificate verification (insecure_skip_verify).
+	// If false, the setting is ignored and certificates are always verified.
+	AllowInsecureTLS bool
+
+	// Record every failed attempt of a log, with its response body cut to
+	// maxRespBodyLen, in the log's attempt history, eg: for debugging flaky receivers.
+	AttemptHistory bool
+
+	// Open the circuit of a webhook after BreakerThreshold receiver failures (timeouts,
+	// connection errors, and 429 or 5xx responses) within BreakerWindow (or in a row, if
+	// it's 0), deferring its deliveries until BreakerCooldown passes, after which a single
+	// probe delivery decides whether the circuit closes or opens again. 0 disables it.
+	BreakerThreshold int
+	BreakerWindow    time.Duration
+	BreakerCooldown  time.Duration
+}
+
+// Pool is a named pool of delivery workers that only deliver the given events.
+type Pool struct {
+	Name    string
+	Workers int
+	Events  []string
+}
+
+// errResponseMismatch is returned when a 2xx response body doesn't match a webhook's
+// success conditions, ie: it's a logical failure.
+var errResponseMismatch = errors.New("response body doesn't match")
+
+// reURLAction matches the template actions in a webhook URL.
+var reURLAction = regexp.MustCompile(`\{\{.*?\}\}`)
+
+// errSelfURL is returned when a webhook's URL template resolves to listmonk itself.
+var errSelfURL = errors.New("the resolved webhook URL points to listmonk itself")
+
+// ErrPayload is returned by Trigger when an event's data can't be marshalled.
+// The event is recorded as a failed log on every webhook that's subscribed to it.
+var ErrPayload = errors.New("error marshalling webhook payload")
+
+// Manager queues webhook events and delivers them.
+type Manager struct {
+	opt   Opt
+	store Store
+	c     *http.Client
+	log   *log.Logger
+
+	// HTTP clients of the webhooks that have custom TLS settings (eg: a CA bundle),
+	// by webhook ID.
+	clients   map[int]tlsClient
+	clientsMu sync.Mutex
+
+	// In-memory delivery metrics.
+	metrics metrics
+
+	// In-memory circuit breakers of webhooks.
+	breakers breakers
+
+	// Tracer of the delivery spans, which is a no-op if tracing isn't enabled.
+	tracer trace.Tracer
+
+	// rand.Rand isn't safe for concurrent use by the workers.
+	rnd   *rand.Rand
+	rndMu sync.Mutex
+
+	// Compiled filter expressions of webhooks by their source.
+	filters   map[string]*vm.Program
+	filtersMu sync.Mutex
+
+	// Logs that are buffered for a batch insert, and the timer that flushes them.
+	batch      []LogRow
+	batchTimer *time.Timer
+	batchMu    sync.Mutex
+
+	wg     sync.WaitGroup
+	chStop chan struct{}
+}
+
+// tlsClient is an HTTP client with a webhook's custom TLS settings.
+type tlsClient struct {
+	// Hash of the TLS settings that the client was built with.
+	key string
+	c   *http.Client
+}
+
+// logFunc logs the delivery trace of a single webhook.
+type logFunc func(format string, v ...any)
+
+// New returns a new instance of the webhook manager.
+func New(opt Opt, store Store, lo *log.Logger) *Manager {
+	workers := opt.Workers
+	for _, p := range opt.Pools {
+		workers += p.Workers
+	}
+
+	opt.RetryJitter = min(max(opt.RetryJitter, 0), 1)
+	if opt.RandSource == nil {
+		opt.RandSource = rand.NewSource(time.Now().UnixNano())
+	}
+	if opt.InsertBatchWait <= 0 {
+		opt.InsertBatchWait = defaultInsertBatchWait
+	}
+	if opt.TracerProvider == nil {
+		opt.TracerProvider = noop.NewTracerProvider()
+	}
+	if opt.BreakerCooldown <= 0 {
+		opt.BreakerCooldown = defaultBreakerCooldown
+	}
+
+	m := &Manager{
+		opt:     opt,
+		store:   store,
+		clients: make(map[int]tlsClient),
+		rnd:     rand.New(opt.RandSource),
+		filters: make(map[string]*vm.Program),
+		tracer:  opt.TracerProvider.Tracer("github.com/knadh/listmonk/internal/webhooks"),
+		log:     lo,
+		chStop:  make(chan struct{}),
+		breakers: breakers{
+			threshold: opt.BreakerThreshold,
+			window:    opt.BreakerWindow,
+			cooldown:  opt.BreakerCooldown,
+			probeWait: opt.Interval,
+		},
+	}
+	m.c = &http.Client{
+		Transport: m.newTransport(workers, nil),
+	}
+
+	return m
+}
+
+// newTransport returns an HTTP transport with an optional TLS config that
+// checks receivers' IPs against the blocked ranges.
+func (m *Manager) newTransport(maxConns int, tlsConf *tls.Config) *http.Transport {
+	return &http.Transport{
+		DialContext:         m.dialContext,
+		MaxIdleConnsPerHost: maxConns,
+		IdleConnTimeout:     time.Second * 30,
+		TLSClientConfig:     tlsConf,
+	}
+}
+
+// Run starts the delivery workers of the default pool and the dedicated pools.
+// This is a blocking function.
+func (m *Manager) Run() {
+	total := m.opt.Workers
+	for _, p := range m.opt.Pools {
+		total += p.Workers
+	}
+
+	// The default pool delivers everything except the events of the dedicated pools.
+	var (
+		exclude []string
+		n       = 0
+	)
+	for _, p := range m.opt.Pools {
+		exclude = append(exclude, p.Events...)
+
+		for i := 0; i < p.Workers; i++ {
+			m.wg.Add(1)
+			go m.worker(p, nil, m.workerPhase(n, total))
+			n++
+		}
+	}
+
+	def := Pool{Name: "default", Workers: m.opt.Workers}
+	for i := 0; i < def.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker(def, exclude, m.workerPhase(n, total))
+		n++
+	}
+
+	m.wg.Wait()
+}
+
+// Close signals the workers to stop after their current batch and waits for them.
+func (m *Manager) Close() {
+	close(m.chStop)
+	m.wg.Wait()
+
+	if err := m.flushLogs(); err != nil {
+		m.log.Println(err)
+	}
+	m.c.CloseIdleConnections()
+
+	m.clientsMu.Lock()
+	for _, c := range m.clients {
+		c.c.CloseIdleConnections()
+	}
+	m.clientsMu.Unlock()
+
+	// Export the spans of the last deliveries.
+	if tp, ok := m.opt.TracerProvider.(interface{ Shutdown(context.Context) error }); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			m.log.Printf("error shutting down webhook tracing: %v", err)
+		}
+	}
+}
+
+// Trigger queues a delivery of the given event to all the enabled webhooks that
+// are subscribed to it. actor is the user, subscriber, or system process that
+// triggered the event. Webhooks that the event can't be queued for are logged and
+// skipped, and an error is only returned if the event can't be queued at all.
+func (m *Manager) Trigger(event string, data any, actor models.WebhookActor) error {
+	hooks, err := m.store.GetWebhooksByEvent(event)
+	if err != nil {
+		return fmt.Errorf("error fetching webhooks: %v", err)
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	// Every webhook gets its own message ID in the envelope, so marshal
+	// the event data once and reuse it.
+	b, err := json.Marshal(data)
+	if err != nil {
+		// Record the failure on the webhooks instead of silently dropping the
+		// event, as it's likely a bug in the event's producer.
+		for _, h := range hooks {
+			if _, qErr := m.queueFailed(h.ID, event, actor, err); qErr != nil {
+				m.log.Printf("error recording failed %s event on webhook %d: %v", event, h.ID, qErr)
+			}
+		}
+
+		return fmt.Errorf("%w: %s: %v", ErrPayload, event, err)
+	}
+
+	var (
+		ev = models.WebhookEvent{
+			Event:     event,
+			Timestamp: time.Now(),
+			Actor:     actor,
+			Data:      json.RawMessage(b),
+		}
+
+		// Merge patch of the data for the webhooks that use PATCH.
+		patch json.RawMessage
+
+		// Decoded data for evaluating the webhooks' filter expressions and list scopes.
+		env map[string]any
+
+		// Number of webhooks that the event was queued for.
+		n = 0
+	)
+	empty := isEmptyData(b)
+	for i, h := range hooks {
+		// Webhooks are in the order of their weights, so the lighter ones are skipped.
+		if m.opt.MaxPerEvent > 0 && n >= m.opt.MaxPerEvent {
+			m.log.Printf("WARNING: event %s was queued for the max of %d webhooks (webhooks.max_webhooks_per_event), "+
+				"skipping the remaining %d subscribed webhooks", event, m.opt.MaxPerEvent, len(hooks)-i)
+			break
+		}
+
+		e := ev
+		if empty {
+			switch h.EmptyData {
+			case models.WebhookEmptyDataSkip:
+				continue
+			case models.WebhookEmptyDataObject:
+				if string(b) == "null" {
+					e.Data = json.RawMessage("{}")
+				}
+			}
+		}
+
+		if (h.FilterExpression != "" || len(h.ListIDs) > 0) && env == nil {
+			if env, err = filterEnv(b); err != nil {
+				env = map[string]any{}
+				m.log.Printf("error preparing filter of webhook %d for %s: %v", h.ID, event, err)
+			}
+		}
+
+		// Webhooks that are scoped to lists only get the events of their lists. Events
+		// that don't carry any lists, eg: template events, aren't scoped.
+		if len(h.ListIDs) > 0 {
+			if ids := eventListIDs(env); len(ids) > 0 && !hasAnyListID(h.ListIDs, ids) {
+				continue
+			}
+		}
+
+		// Only queue the event if it matches the webhook's filter. Events whose filter
+		// can't be evaluated are queued so that they aren't silently dropped.
+		if h.FilterExpression != "" {
+			if ok, err := m.matchFilter(h.FilterExpression, env); err != nil {
+				m.log.Printf("error evaluating filter of webhook %d for %s: %v", h.ID, event, err)
+			} else if !ok {
+				continue
+			}
+		}
+
+		// Failing to queue the event for one webhook shouldn't fail it for the others,
+		// which may already have it queued, so the webhook is skipped.
+		if isMergePatch(h, event) {
+			if patch == nil {
+				p, err := makeSubscriberPatch(b)
+				if err != nil {
+					m.log.Printf("error making merge patch of %s for webhook %d: %v", event, h.ID, err)
+					continue
+				}
+				patch = p
+			}
+			e.Data = patch
+		}
+
+		if err := m.queueBatched(h.ID, e, m.triggerDelay(event, n, len(hooks))); err != nil {
+			m.log.Printf("error queueing %s for webhook %d: %v", event, h.ID, err)
+			continue
+		}
+		n++
+	}
+
+	return nil
+}
+
+// triggerDelay returns the delay of the first delivery of an event to the nth of the
+// total webhooks that it's queued for. The deliveries of custom events, which can be
+// triggered at will, are staggered over Opt.TriggerSpread and jittered so that an event
+// that's fanned out to many webhooks doesn't cause a burst of deliveries.
+func (m *Manager) triggerDelay(event string, n, total int) time.Duration {
+	if m.opt.TriggerSpread <= 0 || total < 2 || !models.IsCustomWebhookEvent(event) {
+		return 0
+	}
+
+	return m.jitter(m.opt.TriggerSpread * time.Duration(n) / time.Duration(total))
+}
+
+// TriggerWebhook queues a delivery of the given event to a single webhook
+// irrespective of its subscribed events. It returns the ID of the queued log.
+func (m *Manager) TriggerWebhook(id int, event string, data any, actor models.WebhookActor) (int, error) {
+	return m.queue(id, models.WebhookEvent{
+		Event:     event,
+		Timestamp: time.Now(),
+		Actor:     actor,
+		Data:      data,
+	})
+}
+
+// Metrics returns a snapshot of the delivery metrics of webhooks since the manager was started.
+func (m *Manager) Metrics() Metrics {
+	return m.metrics.snapshot()
+}
+
+// TriggerTest queues a test event to a webhook, which is webhook.test if event is empty,
+// irrespective of the webhook's subscribed events. Test deliveries are flagged in the
+// payload and in the X-Listmonk-Test header. It returns the ID of the queued log.
+func (m *Manager) TriggerTest(id int, event string, data any, actor models.WebhookActor) (int, error) {
+	if event == "" {
+		event = models.EventWebhookTest
+	}
+
+	return m.queue(id, models.WebhookEvent{
+		Event:     event,
+		Timestamp: time.Now(),
+		Actor:     actor,
+		Data:      data,
+		Test:      true,
+	})
+}
+
+// DeliveryResult is the outcome of a synchronous delivery.
+type DeliveryResult struct {
+	StatusCode int `json:"status_code"`
+
+	// Response body, up to the webhook's max_response_body, and whether it's truncated.
+	Body      string `json:"body"`
+	Truncated bool   `json:"truncated"`
+
+	Error      string `json:"error"`
+	ErrorClass string `json:"error_class"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// DeliverNow delivers an event to a webhook synchronously, bypassing the queue, and
+// returns the outcome, eg: to show the receiver's response to a test right away. The
+// delivery is flagged as a test, is attempted once, and isn't recorded in the logs. An
+// error is returned only if the delivery couldn't be attempted, eg: an unknown webhook.
+func (m *Manager) DeliverNow(webhookID int, event string, data any, actor models.WebhookActor) (DeliveryResult, error) {
+	w, err := m.store.GetWebhook(webhookID)
+	if err != nil {
+		return DeliveryResult{}, fmt.Errorf("error fetching webhook: %v", err)
+	}
+
+	if event == "" {
+		event = models.EventWebhookTest
+	}
+	ev := models.WebhookEvent{
+		Event:     event,
+		Timestamp: time.Now(),
+		Actor:     actor,
+		Data:      data,
+		Test:      true,
+	}
+	key, b, err := makeMessage(&ev)
+	if err != nil {
+		return DeliveryResult{}, err
+	}
+
+	var (
+		res = &DeliveryResult{}
+		l   = PendingLog{
+			WebhookLog: models.WebhookLog{
+				WebhookID:      w.ID,
+				Event:          ev.Event,
+				Status:         models.WebhookLogStatusPending,
+				IdempotencyKey: key,
+				MessageID:      ev.MessageID,
+				Test:           true,
+				Payload:        json.RawMessage(b),
+				CreatedAt:      ev.Timestamp,
+			},
+			Webhook: w,
+			result:  res,
+		}
+	)
+
+	start := time.Now()
+	m.deliverWebhook(l, m.deliveryLogger(w))
+	res.DurationMS = time.Since(start).Milliseconds()
+
+	return *res, nil
+}
+
+// Preview is a delivery request that's rendered without being sent.
+type Preview struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+
+	// Body before it's compressed (if the webhook compresses it), which is what's signed.
+	Body      string `json:"body"`
+	Signature string `json:"signature"`
+
+	// Error that rendering the request failed with, eg: an invalid URL template.
+	Error string `json:"error"`
+}
+
+// Preview renders the request that an event would be delivered to a webhook with,
+// without sending it, eg: for checking a webhook's payload settings before saving it.
+// The request is rendered by the delivery code and is identical to a real one except
+// for its message ID, idempotency key, and time, and the delivery ID, which is 0.
+// Basic auth credentials are masked.
+func (m *Manager) Preview(w models.Webhook, event string, data any, actor models.WebhookActor) (Preview, error) {
+	if event == "" {
+		event = models.EventWebhookTest
+	}
+	ev := models.WebhookEvent{
+		Event:     event,
+		Timestamp: time.Now(),
+		Actor:     actor,
+		Data:      data,
+	}
+	key, b, err := makeMessage(&ev)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	var (
+		res = &DeliveryResult{}
+		out = &Preview{Headers: map[string]string{}}
+		l   = PendingLog{
+			WebhookLog: models.WebhookLog{
+				WebhookID:      w.ID,
+				Event:          ev.Event,
+				Status:         models.WebhookLogStatusPending,
+				IdempotencyKey: key,
+				MessageID:      ev.MessageID,
+				Payload:        json.RawMessage(b),
+				CreatedAt:      ev.Timestamp,
+			},
+			Webhook: w,
+			result:  res,
+			preview: out,
+		}
+	)
+
+	m.deliverWebhook(l, func(string, ...any) {})
+	out.Error = res.Error
+
+	return *out, nil
+}
+
+// queue assigns a message ID to an event and inserts a pending delivery log
+// for it. The message ID is part of the (signed) payload and is recorded on
+// the log so that the receiver and listmonk can correlate a message.
+func (m *Manager) queue(webhookID int, ev models.WebhookEvent) (int, error) {
+	r, err := m.makeLogRow(webhookID, ev)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.insertLog(r)
+}
+
+// insertLog inserts a pending delivery log and returns its ID.
+func (m *Manager) insertLog(r LogRow) (int, error) {
+	// Insert the buffered logs first so that they keep their place in the sequence.
+	if m.opt.InsertBatchSize >= 2 {
+		m.batchMu.Lock()
+		defer m.batchMu.Unlock()
+
+		if err := m.flushLogsLocked(); err != nil {
+			m.log.Println(err)
+		}
+	}
+
+	id, err := m.store.CreateLog(r)
+	if err != nil {
+		return 0, fmt.Errorf("error queueing webhook log: %v", err)
+	}
+
+	return id, nil
+}
+
+// makeLogRow assigns a message ID to an event and prepares its delivery log.
+func (m *Manager) makeLogRow(webhookID int, ev models.WebhookEvent) (LogRow, error) {
+	key, b, err := makeMessage(&ev)
+	if err != nil {
+		return LogRow{}, err
+	}
+
+	r := LogRow{
+		WebhookID: webhookID,
+		Event:     ev.Event,
+		Key:       key,
+		MessageID: ev.MessageID,
+		Payload:   json.RawMessage(b),
+		Test:      ev.Test,
+	}
+
+	// Store the payload compressed, if it's smaller.
+	if m.opt.CompressPayloads {
+		if c, err := gzipBytes(b); err == nil && len(c) < len(b) {
+			r.Payload, r.PayloadGZ = json.RawMessage("{}"), c
+		}
+	}
+
+	return r, nil
+}
+
+// queueFailed records an event whose data couldn't be marshalled as a failed log
+// (without data) that's never delivered. It returns the ID of the log.
+func (m *Manager) queueFailed(webhookID int, event string, actor models.WebhookActor, mErr error) (int, error) {
+	ev := models.WebhookEvent{
+		Event:     event,
+		Timestamp: time.Now(),
+		Actor:     actor,
+	}
+	key, b, err := makeMessage(&ev)
+	if err != nil {
+		return 0, err
+	}
+
+	r := LogRow{
+		WebhookID: webhookID,
+		Event:     ev.Event,
+		Key:       key,
+		MessageID: ev.MessageID,
+		Payload:   json.RawMessage(b),
+	}
+	id, err := m.store.CreateFailedLog(r, fmt.Sprintf("%v: %v", ErrPayload, mErr), models.WebhookErrorPayload)
+	if err != nil {
+		return 0, fmt.Errorf("error recording failed webhook log: %v", err)
+	}
+
+	return id, nil
+}
+
+// makeMessage assigns a message ID to an event and returns an idempotency key
+// and the marshalled event.
+func makeMessage(ev *models.WebhookEvent) (string, []byte, error) {
+	key, err := uuid.NewV4()
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating UUID: %v", err)
+	}
+
+	// Time ordered UUID.
+	msgID, err := uuid.NewV7()
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating UUID: %v", err)
+	}
+	ev.MessageID = msgID.String()
+
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrPayload, err)
+	}
+
+	return key.String(), b, nil
+}
+
+// workerPhase returns the delay of the first poll of the nth of total workers.
+func (m *Manager) workerPhase(n, total int) time.Duration {
+	if !m.opt.Stagger || total < 2 {
+		return 0
+	}
+
+	return m.opt.Interval * time.Duration(n) / time.Duration(total)
+}
+
+// worker polls for pending logs at the configured interval, after an initial phase
+// delay, and delivers them. It only picks the logs of the pool's events, if any, and
+// skips the logs of the excluded events.
+func (m *Manager) worker(p Pool, exclude []string, phase time.Duration) {
+	defer m.wg.Done()
+
+	if phase > 0 {
+		select {
+		case <-m.chStop:
+			return
+		case <-time.After(phase):
+		}
+	}
+
+	t := time.NewTicker(m.opt.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-m.chStop:
+			return
+		case <-t.C:
+			m.processPendingLogs(p, exclude)
+		}
+	}
+}
+
+// processPendingLogs fetches a batch of due logs of a pool and delivers them.
+func (m *Manager) processPendingLogs(p Pool, exclude []string) {
+	logs, err := m.store.NextLogs(m.opt.BatchSize, m.opt.LeaseDuration, p.Events, exclude)
+	if err != nil {
+		m.log.Printf("error fetching pending webhook logs (%s pool): %v", p.Name, err)
+		return
+	}
+
+	for _, l := range logs {
+		if err := json.Unmarshal(l.WebhookRaw, &l.Webhook); err != nil {
+			m.log.Printf("error reading webhook %d for log %d: %v", l.WebhookID, l.ID, err)
+			continue
+		}
+
+		if err := l.UnpackPayload(); err != nil {
+			m.log.Printf("error decompressing payload of log %d: %v", l.ID, err)
+			continue
+		}
+
+		// Don't deliver stale events that have sat in the queue for longer
+		// than the webhook's max age, eg: while the receiver was down.
+		if d, err := time.ParseDuration(l.Webhook.MaxEventAge); err == nil && d > 0 {
+			if age := time.Since(queuedAt(l)); age > d {
+				m.updateLogExpired(l, fmt.Sprintf("event expired after %s in queue (max age %s)", age.Round(time.Second), d))
+				continue
+			}
+		}
+
+		// Don't retry events that are past the webhook's total delivery deadline,
+		// eg: a delivery that was held by an open circuit.
+		if t := deliveryDeadline(l); !t.IsZero() && !time.Now().Before(t) {
+			m.updateLogExpired(l, fmt.Sprintf("delivery deadline of %s exceeded after %d attempts", l.Webhook.TotalDeadline, l.Attempts))
+			continue
+		}
+
+		// Deliveries that the receiver accepted but didn't confirm in time are redelivered
+		// until the attempts run out.
+		if l.ConfirmBy.Valid && l.Attempts > l.Webhook.MaxRetries {
+			m.updateLogFailed(l, l.Attempts, l.ResponseCode, l.ResponseBody,
+				fmt.Sprintf("delivery not confirmed within %s after %d attempts", l.Webhook.ConfirmWindow, l.Attempts),
+				models.WebhookErrorUnconfirmed, null.Time{}, false)
+			continue
+		}
+
+		// Hold the deliveries of webhooks whose circuit is open, without counting them as
+		// attempts, until a probe delivery has gone through.
+		lo := m.deliveryLogger(l.Webhook)
+		ok, until, probe := m.breakers.allow(l.WebhookID, time.Now())
+		if !ok {
+			lo("log %d: circuit open. deferring delivery until %s", l.ID, until.Format(time.RFC3339))
+			if err := m.store.DeferLog(l.ID, until); err != nil {
+				m.log.Printf("error deferring webhook log %d: %v", l.ID, err)
+			}
+			continue
+		}
+		if probe {
+			lo("log %d: circuit half-open. probing the receiver", l.ID)
+		}
+
+		m.deliverWebhook(l, lo)
+	}
+}
+
+// Breaker returns the state of a webhook's delivery circuit breaker.
+func (m *Manager) Breaker(webhookID int) models.WebhookBreaker {
+	return m.breakers.get(webhookID)
+}
+
+// deliveryLogger returns a logger for tracing the deliveries of a webhook.
+// Only webhooks that have debugging enabled are logged so that one webhook
+// can be debugged without the others drowning the logs.
+func (m *Manager) deliveryLogger(w models.Webhook) logFunc {
+	if !w.Debug {
+		return func(string, ...any) {}
+	}
+
+	prefix := fmt.Sprintf("webhook %d (%s): ", w.ID, w.Name)
+	return func(format string, v ...any) {
+		m.log.Printf(prefix+format, v...)
+	}
+}
+
+// deliverWebhook posts a log's payload to its webhook and records the outcome.
+func (m *Manager) deliverWebhook(l PendingLog, lo logFunc) {
+	var (
+		w        = l.Webhook
+		attempts = l.Attempts + 1
+	)
+
+	// Clear the response of the previous attempt.
+	l.ResponseHash, l.ResponseTruncated = "", false
+
+	// Synchronous deliveries aren't counted in the metrics.
+	if l.result == nil {
+		m.metrics.attempt(l.WebhookID, attempts)
+	}
+
+	timeout := defaultTimeout
+	if d, err := time.ParseDuration(w.Timeout); err == nil && d > 0 {
+		timeout = d
+	}
+
+	method := w.HTTPMethod
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	// Every attempt is a span that ends with the attempt, whatever its outcome.
+	ctx, span := m.startSpan(context.Background(), l, attempts, method)
+	defer span.End()
+	l.span = span
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Send the event as a Slack or Discord message instead of the envelope, in which case,
+	// the envelope's options (confirmation URL, field naming, and root key) don't apply.
+	var (
+		payload = []byte(l.Payload)
+		format  func([]byte) ([]byte, error)
+	)
+	switch w.PayloadFormat {
+	case models.WebhookPayloadFormatSlack:
+		format = formatSlack
+	case models.WebhookPayloadFormatDiscord:
+		format = formatDiscord
+	}
+	msg := format != nil
+	if msg {
+		b, err := format(payload)
+		if err != nil {
+			m.handleDeliveryError(l, attempts, 0, "", err, lo)
+			return
+		}
+		payload = b
+	}
+
+	// Embed the URL at which the receiver has to confirm the delivery, if it has to.
+	// Synchronous deliveries aren't stored and can't be confirmed.
+	if !msg && confirmWindow(w) > 0 && l.result == nil {
+		b, err := m.addConfirmURL(&l, payload)
+		if err != nil {
+			m.handleDeliveryError(l, attempts, 0, "", err, lo)
+			return
+		}
+		payload = b
+	}
+
+	// camelCase the keys for receivers whose schemas aren't snake_case.
+	if !msg && (w.FieldNaming == models.WebhookFieldNamingCamel || w.FieldNaming == models.WebhookFieldNamingCamelNested) {
+		b, err := camelizeKeys(payload, w.FieldNaming == models.WebhookFieldNamingCamelNested)
+		if err != nil {
+			m.handleDeliveryError(l, attempts, 0, "", err, lo)
+			return
+		}
+		payload = b
+	}
+
+	// Namespace the envelope under a root key for receivers with fixed schemas.
+	if !msg && w.PayloadRootKey != "" {
+		b, err := json.Marshal(map[string]json.RawMessage{w.PayloadRootKey: payload})
+		if err != nil {
+			m.handleDeliveryError(l, attempts, 0, "", err, lo)
+			return
+		}
+		payload = b
+	}
+
+	// Transcode the body for receivers that don't accept UTF-8.
+	payload, err := encodeCharset(payload, w.Charset)
+	if err != nil {
+		m.handleDeliveryError(l, attempts, 0, "", err, lo)
+		return
+	}
+
+	// Resolve the URL template, if any, against the event.
+	l.URL, err = m.resolveURL(w, l.Payload)
+	if err != nil {
+		m.handleDeliveryError(l, attempts, 0, "", err, lo)
+		return
+	}
+
+	// Compress the body for receivers that prefer it. The signature is still
+	// of the uncompressed payload so that it verifies after decompression.
+	reqBody := payload
+	if w.Compress {
+		b, err := gzipBytes(payload)
+		if err != nil {
+			m.handleDeliveryError(l, attempts, 0, "", err, lo)
+			return
+		}
+		reqBody = b
+	}
+
+	// The Content-Length is set from the (compressed) body's reader.
+	req, err := http.NewRequestWithContext(ctx, method, l.URL, bytes.NewReader(reqBody))
+	if err != nil {
+		m.handleDeliveryError(l, attempts, 0, "", err, lo)
+		return
+	}
+	if w.Compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	// Close the connection after the request for receivers that misbehave
+	// with keep-alive.
+	req.Close = w.DisableKeepAlive
+
+	cType := "application/json"
+	if w.ContentType != "" {
+		cType = w.ContentType
+	}
+	if isMergePatch(w, l.Event) {
+		cType = models.WebhookContentTypeMergePatch
+	}
+	if w.Charset != "" && !strings.Contains(cType, "charset=") {
+		cType += "; charset=" + w.Charset
+	}
+	req.Header.Set("Content-Type", cType)
+	req.Header.Set("User-Agent", "listmonk")
+	req.Header.Set("X-Listmonk-Event", l.Event)
+	req.Header.Set("X-Listmonk-Delivery", strconv.Itoa(l.ID))
+	req.Header.Set("X-Listmonk-Message-Id", l.MessageID)
+	req.Header.Set("X-Listmonk-Idempotency-Key", l.IdempotencyKey)
+	req.Header.Set("X-Listmonk-Attempt", strconv.Itoa(attempts))
+	if l.Test {
+		req.Header.Set("X-Listmonk-Test", "true")
+	}
+
+	// Total attempts (first + retries), so that the receiver knows when it's the last one.
+	req.Header.Set("X-Listmonk-Max-Attempts", strconv.Itoa(w.MaxRetries+1))
+
+	// Payload schema version served to the receiver, recorded on the log.
+	l.PayloadVersion = payloadVersion(w)
+	req.Header.Set("X-Listmonk-Payload-Version", l.PayloadVersion)
+
+	// Custom headers can't override listmonk's own headers. The auth headers
+	// are set after them.
+	for k, v := range w.Headers {
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), "X-Listmonk-") {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	switch w.AuthType {
+	case models.WebhookAuthTypeBasic:
+		// Credentials go in Authorization unless the receiver expects them in another header.
+		h := w.AuthBasicHeader
+		if h == "" {
+			h = "Authorization"
+		}
+		req.Header.Set(h, "Basic "+base64.StdEncoding.EncodeToString([]byte(w.AuthBasicUser+":"+w.AuthBasicPass)))
+	case models.WebhookAuthTypeHMAC:
+		// Sign either the time of this attempt or the time the event was queued.
+		t := time.Now()
+		if w.AuthHMACTimestamp == models.WebhookHMACTimestampCreated {
+			t = l.CreatedAt
+		}
+		ts := strconv.FormatInt(t.Unix(), 10)
+		req.Header.Set("X-Listmonk-Timestamp", ts)
+
+		// Tell the receiver how much skew to accept on the timestamp.
+		if d, err := time.ParseDuration(w.SignatureTolerance); err == nil && d > 0 {
+			req.Header.Set("X-Listmonk-Tolerance", strconv.Itoa(int(d.Seconds())))
+		}
+
+		var (
+			parts  = []string{ts}
+			signed = []string{"timestamp"}
+		)
+
+		// Optionally sign an expiry after which the receiver should reject the request.
+		if ttl, err := time.ParseDuration(w.AuthHMACTTL); err == nil && ttl > 0 {
+			exp := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+			req.Header.Set("X-Listmonk-Expires", exp)
+			parts = append(parts, exp)
+			signed = append(signed, "expires")
+		}
+
+		// Optionally bind the signature to the endpoint it's sent to.
+		switch w.AuthHMACSignURL {
+		case models.WebhookHMACSignURLPath:
+			parts = append(parts, req.URL.RequestURI())
+			signed = append(signed, "path")
+		case models.WebhookHMACSignURLFull:
+			parts = append(parts, req.URL.String())
+			signed = append(signed, "url")
+		}
+
+		req.Header.Set("X-Listmonk-Signed", strings.Join(append(signed, "body"), ","))
+		alg := w.HMACAlgorithm
+		if alg == "" {
+			alg = models.WebhookHMACSHA256
+		}
+		req.Header.Set("X-Listmonk-Signature", alg+"="+computeHMAC(alg, w.AuthHMACSecret, parts, payload))
+
+		// During a secret rotation, also sign with the previous secret so that receivers
+		// that haven't switched to the new secret yet can verify the delivery.
+		if prev := w.PrevHMACSecret(time.Now()); prev != "" {
+			req.Header.Set("X-Listmonk-Signature-Previous", alg+"="+computeHMAC(alg, prev, parts, payload))
+		}
+	}
+
+	injectTrace(ctx, req)
+
+	// Previews are rendered as they'd be sent, but aren't sent.
+	if l.preview != nil {
+		l.preview.Method, l.preview.URL, l.preview.Body = method, l.URL, string(payload)
+		for k := range req.Header {
+			l.preview.Headers[k] = req.Header.Get(k)
+		}
+		l.preview.Signature = req.Header.Get("X-Listmonk-Signature")
+
+		if w.AuthType == models.WebhookAuthTypeBasic {
+			h := http.CanonicalHeaderKey(w.AuthBasicHeader)
+			if h == "" {
+				h = "Authorization"
+			}
+			l.preview.Headers[h] = "Basic " + redactedText
+		}
+		return
+	}
+
+	lo("log %d: sending %s (attempt %d, %d bytes) with %s to %s", l.ID, l.Event, attempts, len(reqBody), method, l.URL)
+
+	// This is a foot-gun, so warn on every delivery irrespective of debug logging.
+	if w.InsecureSkipVerify {
+		if m.opt.AllowInsecureTLS {
+			m.log.Printf("WARNING: webhook %d (%s): TLS certificate verification is DISABLED (insecure_skip_verify). "+
+				"Deliveries to %s can be intercepted", w.ID, w.Name, w.URL)
+		} else {
+			m.log.Printf("WARNING: webhook %d (%s): insecure_skip_verify is ignored as webhooks.allow_insecure_tls is disabled", w.ID, w.Name)
+		}
+	}
+
+	client, err := m.client(w)
+	if err != nil {
+		m.handleDeliveryError(l, attempts, 0, "", err, lo)
+		return
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		m.handleDeliveryError(l, attempts, 0, "", err, lo)
+		return
+	}
+	defer resp.Body.Close()
+	if l.result == nil {
+		m.metrics.latency(l.WebhookID, time.Since(start))
+	}
+	traceResponse(span, resp.StatusCode, time.Since(start))
+
+	// Only a part of the body, up to the webhook's max_response_body, is recorded.
+	recLen := maxRespBodyLen
+	if w.MaxResponseBody > 0 {
+		recLen = w.MaxResponseBody
+	}
+
+	// Read a part of the response body and stream the rest to discard it so that the
+	// connection can be reused. A larger part is read if it has to be matched. The
+	// whole body is hashed on the way so that large responses can be verified or
+	// de-duplicated by their checksum even though only a part is recorded.
+	maxLen := int64(recLen)
+	if w.SuccessBodyRegex != "" || w.SuccessBodyJSON != "" {
+		maxLen = max(maxLen, maxMatchBodyLen)
+	}
+	var (
+		h = sha256.New()
+		r = io.TeeReader(resp.Body, h)
+	)
+	fullBody, _ := io.ReadAll(io.LimitReader(r, maxLen))
+	rest, _ := io.Copy(io.Discard, r)
+
+	// Redact the sensitive parts of the body before it's logged or recorded. The full
+	// body is redacted before it's cut so that matches across the cut are redacted.
+	body := fullBody
+	if len(m.opt.RedactPatterns) > 0 {
+		body = redactBody(body, m.opt.RedactPatterns)
+	}
+	l.ResponseTruncated = rest > 0 || len(body) > recLen
+	if len(body) > recLen {
+		body = body[:recLen]
+	}
+	l.ResponseHash = hex.EncodeToString(h.Sum(nil))
+
+	lo("log %d: received %s in %s: %q", l.ID, resp.Status, time.Since(start).Round(time.Millisecond), body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		m.handleDeliveryError(l, attempts, resp.StatusCode, string(body), errors.New(resp.Status), lo)
+		return
+	}
+
+	// Receivers may respond with a 2xx for logical failures, eg: {"ok": false}.
+	if err := matchResponseBody(w, fullBody); err != nil {
+		m.handleDeliveryError(l, attempts, resp.StatusCode, string(body), err, lo)
+		return
+	}
+
+	m.updateLogSuccess(l, attempts, resp.StatusCode, string(body))
+}
+
+// resolveURL resolves a webhook's URL template, if it has one, against an event,
+// eg: https://api/subscribers/{{ .Data.subscriber.id }}/events, and checks the result.
+func (m *Manager) resolveURL(w models.Webhook, payload json.RawMessage) (string, error) {
+	if !IsURLTemplate(w.URL) {
+		return w.URL, nil
+	}
+
+	tpl, err := parseURLTemplate(w.URL)
+	if err != nil {
+		return "", err
+	}
+
+	// Keep the numbers, eg: IDs, as they are.
+	var ev models.WebhookEvent
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&ev); err != nil {
+		return "", fmt.Errorf("error decoding payload for URL template: %v", err)
+	}
+
+	var b strings.Builder
+	if err := tpl.Execute(&b, ev); err != nil {
+		return "", fmt.Errorf("error resolving URL template: %v", err)
+	}
+
+	u, err := url.Parse(b.String())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid resolved URL: %s", b.String())
+	}
+	if IsSelfHost(u, m.opt.SelfHosts) {
+		return "", errSelfURL
+	}
+
+	return u.String(), nil
+}
+
+// IsURLTemplate checks whether a webhook URL has template actions.
+func IsURLTemplate(u string) bool {
+	return strings.Contains(u, "{{")
+}
+
+// ValidateURLTemplate checks whether a webhook URL template compiles and returns
+// the URL with its actions replaced by a placeholder for static checks.
+func ValidateURLTemplate(u string) (string, error) {
+	if _, err := parseURLTemplate(u); err != nil {
+		return "", err
+	}
+
+	return reURLAction.ReplaceAllString(u, "0"), nil
+}
+
+// parseURLTemplate compiles a webhook URL template. Missing keys are errors so
+// that a URL isn't resolved with blank path params.
+func parseURLTemplate(u string) (*template.Template, error) {
+	return template.New("url").Option("missingkey=error").Parse(u)
+}
+
+// IsSelfHost checks whether a URL's host matches one of the given hosts.
+// Hosts without a port match any port.
+func IsSelfHost(u *url.URL, hosts []string) bool {
+	var (
+		hostPort = URLHostPort(u)
+		host     = strings.ToLower(u.Hostname())
+	)
+	for _, h := range hosts {
+		if h == hostPort {
+			return true
+		}
+
+		if _, _, err := net.SplitHostPort(h); err != nil && strings.Trim(h, "[]") == host {
+			return true
+		}
+	}
+
+	return false
+}
+
+// URLHostPort returns the lowercased host:port of a URL, with the scheme's
+// default port if there's none.
+func URLHostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	return net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
+
+// client returns the HTTP client for a webhook. Webhooks with custom TLS settings
+// get their own client, which is cached until the settings change.
+func (m *Manager) client(w models.Webhook) (*http.Client, error) {
+	insecure := w.InsecureSkipVerify && m.opt.AllowInsecureTLS
+	if w.CABundle == "" && !insecure {
+		return m.c, nil
+	}
+
+	h := sha256.Sum256([]byte(strconv.FormatBool(insecure) + w.CABundle))
+	key := hex.EncodeToString(h[:])
+
+	m.clientsMu.Lock()
+	defer m.clientsMu.Unlock()
+
+	if c, ok := m.clients[w.ID]; ok {
+		if c.key == key {
+			return c.c, nil
+		}
+
+		// The settings have changed.
+		c.c.CloseIdleConnections()
+		delete(m.clients, w.ID)
+	}
+
+	// Trust the webhook's CA bundle in addition to the system's CAs.
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if w.CABundle != "" && !pool.AppendCertsFromPEM([]byte(w.CABundle)) {
+		return nil, errors.New("error parsing the webhook's CA bundle")
+	}
+
+	c := &http.Client{
+		Transport: m.newTransport(m.opt.Workers, &tls.Config{
+			RootCAs:            pool,
+			InsecureSkipVerify: insecure,
+		}),
+	}
+	m.clients[w.ID] = tlsClient{key: key, c: c}
+
+	return c, nil
+}
+
+// handleDeliveryError records a failed attempt and schedules the next retry
+// with an exponential backoff, or marks the log as failed if the webhook's
+// retries have been exhausted.
+func (m *Manager) handleDeliveryError(l PendingLog, attempts, code int, body string, err error, lo logFunc) {
+	var (
+		next     null.Time
+		expired  bool
+		deadline = deliveryDeadline(l)
+	)
+	switch {
+	// Synchronous deliveries are attempted once.
+	case l.result != nil:
+		lo("attempt failed: %v", err)
+
+	// Blocked receivers are a configuration issue that retries won't fix.
+	case attempts > l.Webhook.MaxRetries || errors.Is(err, ErrBlockedAddr):
+		lo("log %d: attempt %d failed: %v. giving up after %d retries", l.ID, attempts, err, l.Webhook.MaxRetries)
+
+	// The event is past the webhook's total delivery deadline.
+	case !deadline.IsZero() && !time.Now().Before(deadline):
+		expired = true
+
+	default:
+		t := time.Now().Add(m.jitter(retryDelay(l.Webhook, attempts)))
+
+		// Defer the retry to the webhook's retry window, if there's one.
+		if l.Webhook.RetryWindow != "" {
+			if w, err := ParseRetryWindow(l.Webhook.RetryWindow, l.Webhook.RetryWindowTZ); err == nil {
+				t = w.Next(t)
+			} else {
+				m.log.Printf("error parsing retry window of webhook %d: %v", l.WebhookID, err)
+			}
+		}
+
+		// Don't schedule a retry that would only happen after the deadline.
+		if !deadline.IsZero() && t.After(deadline) {
+			expired = true
+			break
+		}
+
+		next = null.TimeFrom(t)
+		lo("log %d: attempt %d failed: %v. retrying in %s", l.ID, attempts, err, time.Until(t).Round(time.Second))
+	}
+	if expired {
+		lo("log %d: attempt %d failed: %v. giving up at the delivery deadline of %s", l.ID, attempts, err, l.Webhook.TotalDeadline)
+	}
+
+	// Tell timeouts (slow receivers) apart from connection failures (dead receivers).
+	class := models.WebhookErrorHTTP
+	switch {
+	case errors.Is(err, errResponseMismatch):
+		class = models.WebhookErrorResponse
+	case code == 0:
+		class = classifyError(err)
+	}
+
+	msg := err.Error()
+	if class == models.WebhookErrorTimeout {
+		msg = fmt.Sprintf("request timed out (timeout %s): %v", l.Webhook.Timeout, err)
+	}
+	if expired {
+		msg = fmt.Sprintf("delivery deadline of %s exceeded after %d attempts: %s", l.Webhook.TotalDeadline, attempts, msg)
+	}
+	traceError(l.span, err, next.Valid)
+
+	// Count the failures that point to a struggling receiver towards its circuit breaker.
+	// Any other response shows that the receiver is up. Test deliveries aren't counted.
+	if l.result == nil && !l.Test {
+		switch {
+		case isReceiverFailure(class, code):
+			if m.breakers.failure(l.WebhookID, time.Now()) {
+				m.log.Printf("opened the circuit of webhook %d after failed deliveries. deferring deliveries for %s", l.WebhookID, m.opt.BreakerCooldown)
+			}
+		case code > 0:
+			m.closeBreaker(l.WebhookID)
+		}
+	}
+
+	m.updateLogFailed(l, attempts, code, body, msg, class, next, expired)
+}
+
+// deliveryDeadline returns the time by which a log's event has to be delivered as per
+// its webhook's total delivery deadline, or a zero time if there's no deadline.
+func deliveryDeadline(l PendingLog) time.Time {
+	d, err := time.ParseDuration(l.Webhook.TotalDeadline)
+	if err != nil || d <= 0 {
+		return time.Time{}
+	}
+
+	return queuedAt(l).Add(d)
+}
+
+// queuedAt returns the time from which a log's age counts: the time it was last
+// retried manually, which gives it a fresh max age and deadline, or its creation.
+func queuedAt(l PendingLog) time.Time {
+	if l.RequeuedAt.Valid {
+		return l.RequeuedAt.Time
+	}
+
+	return l.CreatedAt
+}
+
+// retryDelay returns the delay before retrying a delivery after the given failed attempt
+// with the webhook's backoff strategy and retry interval, capped at maxRetryBackoff.
+func retryDelay(w models.Webhook, attempts int) time.Duration {
+	interval := retryBackoff
+	if d, err := time.ParseDuration(w.RetryInterval); err == nil && d >= time.Second {
+		interval = d
+	}
+
+	// The delay is grown step by step up to the cap so that large attempts don't overflow.
+	d := interval
+	switch w.BackoffStrategy {
+	case models.WebhookBackoffFixed:
+	case models.WebhookBackoffLinear:
+		for i := 1; i < attempts && d < maxRetryBackoff; i++ {
+			d += interval
+		}
+	default:
+		for i := 1; i < attempts && d < maxRetryBackoff; i++ {
+			d *= 2
+		}
+	}
+
+	return min(d, maxRetryBackoff)
+}
+
+// jitter randomly moves a delay back or forth by up to the RetryJitter fraction of it.
+func (m *Manager) jitter(d time.Duration) time.Duration {
+	if m.opt.RetryJitter == 0 {
+		return d
+	}
+
+	m.rndMu.Lock()
+	f := m.rnd.Float64()
+	m.rndMu.Unlock()
+
+	// f is in [0, 1), which is mapped to [-jitter, +jitter).
+	return d + time.Duration(float64(d)*m.opt.RetryJitter*(2*f-1))
+}
+
+// matchResponseBody checks a 2xx response body against a webhook's optional success
+// conditions: a regexp that the body should match, and a `path=value` where the value at
+// the dot separated path in the JSON body should be value, eg: `ok=true` or `data.status=done`.
+func matchResponseBody(w models.Webhook, body []byte) error {
+	if w.SuccessBodyRegex != "" {
+		re, err := regexp.Compile(w.SuccessBodyRegex)
+		if err != nil {
+			return fmt.Errorf("invalid success regexp: %v", err)
+		}
+
+		if !re.Match(body) {
+			return fmt.Errorf("%w success regexp %s", errResponseMismatch, w.SuccessBodyRegex)
+		}
+	}
+
+	if w.SuccessBodyJSON != "" {
+		path, expected, _ := strings.Cut(w.SuccessBodyJSON, "=")
+
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return fmt.Errorf("%w success condition %s: invalid JSON body", errResponseMismatch, w.SuccessBodyJSON)
+		}
+
+		// Walk the path.
+		for _, k := range strings.Split(path, ".") {
+			obj, ok := v.(map[string]any)
+			if !ok {
+				v = nil
+				break
+			}
+			v = obj[k]
+		}
+
+		// Compare the JSON representation of the value, eg: true, 1, "ok"
+		// (unquoted strings are also accepted).
+		b, _ := json.Marshal(v)
+		if got := string(b); got != expected && strings.Trim(got, `"`) != expected {
+			return fmt.Errorf("%w success condition %s: got %s", errResponseMismatch, w.SuccessBodyJSON, got)
+		}
+	}
+
+	return nil
+}
+
+// classifyError returns the class of an error that occurred while sending a request.
+func classifyError(err error) string {
+	var (
+		netErr  net.Error
+		dnsErr  *net.DNSError
+		opErr   *net.OpError
+		certErr *tls.CertificateVerificationError
+		recErr  tls.RecordHeaderError
+		uaErr   x509.UnknownAuthorityError
+		hostErr x509.HostnameError
+	)
+
+	switch {
+	case errors.Is(err, ErrBlockedAddr):
+		return models.WebhookErrorBlocked
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return models.WebhookErrorTimeout
+	case errors.As(err, &dnsErr):
+		return models.WebhookErrorDNS
+	case errors.As(err, &certErr), errors.As(err, &recErr), errors.As(err, &uaErr), errors.As(err, &hostErr):
+		return models.WebhookErrorTLS
+	case errors.As(err, &opErr):
+		return models.WebhookErrorConnection
+	}
+
+	return models.WebhookErrorRequest
+}
+
+// closeBreaker closes the circuit of a webhook whose receiver has responded.
+func (m *Manager) closeBreaker(webhookID int) {
+	if m.breakers.reset(webhookID) {
+		m.log.Printf("closed the circuit of webhook %d as its receiver responded", webhookID)
+	}
+}
+
+// isReceiverFailure returns whether a delivery error of the given class and response code
+// points to a receiver that's down or overloaded, as opposed to a misconfiguration.
+func isReceiverFailure(class string, code int) bool {
+	switch class {
+	case models.WebhookErrorTimeout, models.WebhookErrorConnection:
+		return true
+	case models.WebhookErrorHTTP:
+		return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+	}
+
+	return false
+}
+
+func (m *Manager) updateLogSuccess(l PendingLog, attempts, code int, body string) {
+	if l.result != nil {
+		l.result.StatusCode, l.result.Body, l.result.Truncated = code, body, l.ResponseTruncated
+		return
+	}
+
+	m.metrics.outcome(l.WebhookID, true)
+
+	// Test deliveries don't count towards the webhook's breaker or failures.
+	if !l.Test {
+		m.closeBreaker(l.WebhookID)
+	}
+
+	// Deliveries that have to be confirmed stay pending until the receiver confirms them.
+	var confirmBy null.Time
+	if d := confirmWindow(l.Webhook); d > 0 {
+		confirmBy = null.TimeFrom(time.Now().Add(d))
+	}
+
+	if err := m.store.UpdateLogSuccess(l.WebhookLog, attempts, code, body, confirmBy); err != nil {
+		m.log.Printf("error updating webhook log %d: %v", l.ID, err)
+	}
+
+	// A successful delivery breaks the webhook's streak of failures.
+	if !l.Test {
+		if err := m.store.ResetFailures(l.WebhookID); err != nil {
+			m.log.Printf("error resetting failures of webhook %d: %v", l.WebhookID, err)
+		}
+	}
+}
+
+func (m *Manager) updateLogFailed(l PendingLog, attempts, code int, body, errMsg, errClass string, next null.Time, expired bool) {
+	if l.result != nil {
+		l.result.StatusCode, l.result.Body, l.result.Truncated = code, body, l.ResponseTruncated
+		l.result.Error, l.result.ErrorClass = errMsg, errClass
+		return
+	}
+
+	m.metrics.outcome(l.WebhookID, false)
+
+	if err := m.store.UpdateLogFailed(l.WebhookLog, attempts, code, body, errMsg, errClass, next, expired); err != nil {
+		m.log.Printf("error updating webhook log %d: %v", l.ID, err)
+	}
+
+	if m.opt.AttemptHistory {
+		if len(body) > maxRespBodyLen {
+			body = body[:maxRespBodyLen]
+		}
+
+		a := models.WebhookAttempt{
+			Attempt:      attempts,
+			ResponseCode: code,
+			ResponseBody: body,
+			Error:        errMsg,
+			ErrorClass:   errClass,
+			CreatedAt:    time.Now(),
+		}
+		if err := m.store.AppendLogAttempt(l.ID, a); err != nil {
+			m.log.Printf("error recording attempt of webhook log %d: %v", l.ID, err)
+		}
+	}
+
+	// Count permanent failures (no more retries) towards the webhook's failure threshold.
+	if !next.Valid && !l.Test {
+		m.recordFailure(l.WebhookID)
+	}
+}
+
+// recordFailure counts a permanently failed delivery towards a webhook's consecutive
+// failures, which disables the webhook once they reach its failure threshold.
+func (m *Manager) recordFailure(webhookID int) {
+	failures, threshold, err := m.store.RecordFailure(webhookID)
+	if err != nil {
+		m.log.Printf("error recording failure of webhook %d: %v", webhookID, err)
+		return
+	}
+
+	if threshold > 0 && failures == threshold {
+		m.log.Printf("disabled webhook %d after %d consecutive failed deliveries", webhookID, failures)
+	}
+}
+
+func (m *Manager) updateLogExpired(l PendingLog, msg string) {
+	if err := m.store.UpdateLogExpired(l.ID, msg); err != nil {
+		m.log.Printf("error updating webhook log %d: %v", l.ID, err)
+	}
+}
+
+// payloadVersion returns the payload schema version to serve to a webhook: the version
+// that's pinned on the webhook, or the latest.
+func payloadVersion(w models.Webhook) string {
+	if slices.Contains(models.WebhookPayloadVersions, w.PayloadVersion) {
+		return w.PayloadVersion
+	}
+
+	return models.WebhookPayloadVersions[len(models.WebhookPayloadVersions)-1]
+}
+
+// redactBody replaces the matches of the patterns in a response body with [redacted].
+// If a pattern has groups, only the part that matches the first group is replaced,
+// eg: "token":"([^"]+)" redacts the token's value but keeps the key.
+func redactBody(b []byte, patterns []*regexp.Regexp) []byte {
+	for _, re := range patterns {
+		idx := re.FindAllSubmatchIndex(b, -1)
+		if len(idx) == 0 {
+			continue
+		}
+
+		var (
+			out  = make([]byte, 0, len(b))
+			last = 0
+		)
+		for _, m := range idx {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			out = append(out, b[last:start]...)
+			out = append(out, redactedText...)
+			last = end
+		}
+		b = append(out, b[last:]...)
+	}
+
+	return b
+}
+
+// isEmptyData checks whether the JSON data of an event is empty, ie: null, {}, or [].
+func isEmptyData(b []byte) bool {
+	switch string(bytes.TrimSpace(b)) {
+	case "null", "{}", "[]":
+		return true
+	}
+
+	return false
+}
+
+// isMergePatch checks whether an event's data is sent to a webhook as
+// a JSON merge patch (RFC 7386) of the record's previous state.
+func isMergePatch(w models.Webhook, event string) bool {
+	return w.HTTPMethod == http.MethodPatch && event == models.EventSubscriberUpdated
+}
+
+// makeSubscriberPatch takes the JSON data of a subscriber update event that has the
+// subscriber's previous state and returns the data with the subscriber replaced by
+// the merge patch from the previous state to the new one. The subscriber's ID and
+// UUID are always retained for identifying the subscriber.
+func makeSubscriberPatch(data []byte) (json.RawMessage, error) {
+	var d struct {
+		Subscriber map[string]any `json:"subscriber"`
+		Previous   map[string]any `json:"previous"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("error reading webhook payload: %v", err)
+	}
+
+	patch := mergePatch(d.Previous, d.Subscriber)
+	patch["id"] = d.Subscriber["id"]
+	patch["uuid"] = d.Subscriber["uuid"]
+
+	b, err := json.Marshal(map[string]any{"subscriber": patch})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling webhook payload: %v", err)
+	}
+
+	return b, nil
+}
+
+// mergePatch returns the JSON merge patch (RFC 7386) that turns a into b.
+// Fields that are removed in b are set to null.
+func mergePatch(a, b map[string]any) map[string]any {
+	out := map[string]any{}
+	for k, v := range b {
+		old, ok := a[k]
+		if !ok {
+			out[k] = v
+			continue
+		}
+
+		// Recurse into nested objects.
+		om, ok1 := old.(map[string]any)
+		nm, ok2 := v.(map[string]any)
+		if ok1 && ok2 {
+			if p := mergePatch(om, nm); len(p) > 0 {
+				out[k] = p
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(old, v) {
+			out[k] = v
+		}
+	}
+
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			out[k] = nil
+		}
+	}
+
+	return out
+}
+
+// camelizeKeys camelCases the top-level keys of a JSON object, eg: message_id => messageId,
+// or with nested, the keys of all objects in it.
+func camelizeKeys(b []byte, nested bool) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("error decoding payload for field naming: %v", err)
+	}
+
+	return json.Marshal(camelizeValue(v, nested, true))
+}
+
+// camelizeValue camelCases the keys of objects in v. Objects below the top level
+// are only camelCased with nested.
+func camelizeValue(v any, nested, top bool) any {
+	switch o := v.(type) {
+	case map[string]any:
+		if !top && !nested {
+			return o
+		}
+
+		out := make(map[string]any, len(o))
+		for k, val := range o {
+			out[camelCase(k)] = camelizeValue(val, nested, false)
+		}
+		return out
+
+	case []any:
+		if !nested {
+			return o
+		}
+
+		for i, val := range o {
+			o[i] = camelizeValue(val, nested, false)
+		}
+		return o
+	}
+
+	return v
+}
+
+// camelCase converts a snake_case string to camelCase, eg: message_id => messageId.
+func camelCase(s string) string {
+	if !strings.Contains(s, "_") {
+		return s
+	}
+
+	var (
+		b     strings.Builder
+		upper = false
+	)
+	for i, r := range s {
+		if r == '_' {
+			upper = i > 0
+			continue
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// encodeCharset transcodes a UTF-8 JSON body to the given charset. Characters that
+// the charset can't represent are escaped as JSON \uXXXX sequences, which is lossless
+// as all non-ASCII characters in JSON are within strings.
+func encodeCharset(b []byte, charset string) ([]byte, error) {
+	if charset == "" {
+		return b, nil
+	}
+
+	cs, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unknown charset %s: %v", charset, err)
+	}
+
+	var (
+		enc = cs.NewEncoder()
+		out = make([]byte, 0, len(b))
+	)
+	for _, r := range string(b) {
+		if r < utf8.RuneSelf {
+			out = append(out, byte(r))
+			continue
+		}
+
+		if e, err := enc.Bytes([]byte(string(r))); err == nil {
+			out = append(out, e...)
+			continue
+		}
+
+		// Escape the character, as a surrogate pair if it's outside the BMP.
+		if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+			out = fmt.Appendf(out, `\u%04x\u%04x`, r1, r2)
+		} else {
+			out = fmt.Appendf(out, `\u%04x`, r)
+		}
+	}
+
+	return out, nil
+}
+
+// gzipBytes returns the gzip compressed bytes of b.
+func gzipBytes(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, fmt.Errorf("error compressing payload: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("error compressing payload: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// computeHMAC returns the hex encoded HMAC signature with the given algorithm (sha256, sha512,
+// or sha1) of the parts (eg: timestamp, expiry, URL path) and the payload joined with ".",
+// eg: "timestamp.payload".
+func computeHMAC(alg, secret string, parts []string, payload []byte) string {
+	fn := sha256.New
+	switch alg {
+	case models.WebhookHMACSHA512:
+		fn = sha512.New
+	case models.WebhookHMACSHA1:
+		fn = sha1.New
+	}
+
+	h := hmac.New(fn, []byte(secret))
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte("."))
+	}
+	h.Write(payload)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CheckTimestamp checks a signature's X-Listmonk-Timestamp (Unix seconds) against the
+// current time, as a receiver would, and returns an error if it's off by more than the
+// tolerance in either direction.
+func CheckTimestamp(ts string, tolerance time.Duration, now time.Time) error {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %s", ts)
+	}
+
+	skew := now.Sub(time.Unix(sec, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > tolerance {
+		return fmt.Errorf("timestamp is off by %s, more than the tolerance of %s", skew.Round(time.Second), tolerance)
+	}
+
+	return nil
+}
+
+// VerifySignature checks an X-Listmonk-Signature header value ({alg}={hex HMAC}) against
+// the HMAC of the signed parts (eg: timestamp) and the payload, as a receiver would. It
+// returns an error if the header isn't a signature of a known algorithm.
+func VerifySignature(secret, signature string, parts []string, payload []byte) (bool, error) {
+	alg, sig, ok := strings.Cut(signature, "=")
+	if !ok {
+		return false, errors.New("signature should be in the form {algorithm}={hex signature}")
+	}
+	switch alg {
+	case models.WebhookHMACSHA256, models.WebhookHMACSHA512, models.WebhookHMACSHA1:
+	default:
+		return false, fmt.Errorf("unknown signature algorithm: %s", alg)
+	}
+
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false, errors.New("signature isn't hex encoded")
+	}
+	exp, _ := hex.DecodeString(computeHMAC(alg, secret, parts, payload))
+
+	// Compare in constant time so that the comparison doesn't leak how much of a
+	// signature is correct.
+	return hmac.Equal(got, exp), nil
+}
@@ -0,0 +1,782 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+// fakeStore is an in-memory Store that records the outcomes of deliveries.
+type fakeStore struct {
+	mu      sync.Mutex
+	hooks   []models.Webhook
+	pending []PendingLog
+	lastID  int
+
+	// Times at which NextLogs was called.
+	polls []time.Time
+
+	// Logs that were recorded as failed without being delivered.
+	failed []fakeFailedLog
+
+	// Outcomes of delivery attempts.
+	updates []fakeUpdate
+
+	// IDs of the logs that were expired.
+	expired []int
+
+	// RecordFailure and ResetFailures calls by webhook ID.
+	failures map[int]int
+	resets   map[int]int
+}
+
+type fakeFailedLog struct {
+	WebhookID int
+	Event     string
+	Payload   json.RawMessage
+	Error     string
+	ErrClass  string
+}
+
+type fakeUpdate struct {
+	ID        int
+	WebhookID int
+	Event     string
+	Success   bool
+	Code      int
+	ErrClass  string
+	Next      null.Time
+	At        time.Time
+}
+
+func newFakeStore(hooks ...models.Webhook) *fakeStore {
+	return &fakeStore{
+		hooks:    hooks,
+		failures: map[int]int{},
+		resets:   map[int]int{},
+	}
+}
+
+func (s *fakeStore) GetWebhooksByEvent(event string) ([]models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []models.Webhook
+	for _, h := range s.hooks {
+		if slices.Contains(h.Events, event) {
+			out = append(out, h)
+		}
+	}
+
+	return out, nil
+}
+
+func (s *fakeStore) GetWebhook(id int) (models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, h := range s.hooks {
+		if h.ID == id {
+			return h, nil
+		}
+	}
+
+	return models.Webhook{}, io.EOF
+}
+
+func (s *fakeStore) CreateLog(r LogRow) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	s.pending = append(s.pending, PendingLog{
+		WebhookLog: models.WebhookLog{
+			ID:             s.lastID,
+			WebhookID:      r.WebhookID,
+			Event:          r.Event,
+			Status:         models.WebhookLogStatusPending,
+			IdempotencyKey: r.Key,
+			MessageID:      r.MessageID,
+			Payload:        r.Payload,
+			PayloadGZ:      r.PayloadGZ,
+			Test:           r.Test,
+			NextRetryAt:    null.TimeFrom(time.Now().Add(r.Delay)),
+			CreatedAt:      time.Now(),
+		},
+	})
+
+	return s.lastID, nil
+}
+
+func (s *fakeStore) CreateLogs(rows []LogRow) error {
+	for _, r := range rows {
+		if _, err := s.CreateLog(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *fakeStore) CreateFailedLog(r LogRow, errMsg, errClass string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	s.failed = append(s.failed, fakeFailedLog{
+		WebhookID: r.WebhookID,
+		Event:     r.Event,
+		Payload:   r.Payload,
+		Error:     errMsg,
+		ErrClass:  errClass,
+	})
+
+	return s.lastID, nil
+}
+
+// NextLogs returns the due pending logs of the given events, which are removed
+// from the queue until they're re-queued.
+func (s *fakeStore) NextLogs(limit int, lease time.Duration, events, exclude []string) ([]PendingLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.polls = append(s.polls, time.Now())
+
+	var (
+		out  []PendingLog
+		rest []PendingLog
+	)
+	for _, l := range s.pending {
+		if len(out) >= limit || l.NextRetryAt.Time.After(time.Now()) ||
+			(len(events) > 0 && !slices.Contains(events, l.Event)) ||
+			slices.Contains(exclude, l.Event) {
+			rest = append(rest, l)
+			continue
+		}
+
+		for _, h := range s.hooks {
+			if h.ID == l.WebhookID {
+				l.WebhookRaw, _ = json.Marshal(h)
+			}
+		}
+		out = append(out, l)
+	}
+	s.pending = rest
+
+	return out, nil
+}
+
+func (s *fakeStore) SetLogConfirmToken(id int, token string) error {
+	return nil
+}
+
+func (s *fakeStore) UpdateLogSuccess(l models.WebhookLog, attempts, code int, body string, confirmBy null.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updates = append(s.updates, fakeUpdate{ID: l.ID, WebhookID: l.WebhookID, Event: l.Event, Success: true, Code: code, At: time.Now()})
+	return nil
+}
+
+func (s *fakeStore) UpdateLogFailed(l models.WebhookLog, attempts, code int, body, errMsg, errClass string, next null.Time, expired bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updates = append(s.updates, fakeUpdate{ID: l.ID, WebhookID: l.WebhookID, Event: l.Event, Code: code, ErrClass: errClass, Next: next, At: time.Now()})
+	return nil
+}
+
+func (s *fakeStore) AppendLogAttempt(id int, a models.WebhookAttempt) error {
+	return nil
+}
+
+func (s *fakeStore) UpdateLogExpired(id int, msg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expired = append(s.expired, id)
+	return nil
+}
+
+func (s *fakeStore) DeferLog(id int, until time.Time) error {
+	return nil
+}
+
+func (s *fakeStore) RecordFailure(webhookID int) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[webhookID]++
+	return s.failures[webhookID], 0, nil
+}
+
+func (s *fakeStore) ResetFailures(webhookID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.resets[webhookID]++
+	return nil
+}
+
+func (s *fakeStore) getUpdates() []fakeUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.updates)
+}
+
+func (s *fakeStore) getPolls() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.polls)
+}
+
+// newTestHook returns an enabled webhook that posts to the given URL.
+func newTestHook(id int, url string, events ...string) models.Webhook {
+	h := models.Webhook{
+		Name:   "test",
+		URL:    url,
+		Status: models.WebhookStatusEnabled,
+		Events: events,
+	}
+	h.ID = id
+
+	return h
+}
+
+func newTestManager(opt Opt, s Store) *Manager {
+	if opt.Workers == 0 {
+		opt.Workers = 1
+	}
+	if opt.BatchSize == 0 {
+		opt.BatchSize = 10
+	}
+	if opt.Interval == 0 {
+		opt.Interval = time.Millisecond * 10
+	}
+	if opt.LeaseDuration == 0 {
+		opt.LeaseDuration = time.Minute
+	}
+
+	return New(opt, s, log.New(io.Discard, "", 0))
+}
+
+func TestDeliveryTimeout(t *testing.T) {
+	// A receiver that doesn't respond until the test is done.
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	var (
+		hook = newTestHook(1, srv.URL)
+		s    = newFakeStore()
+		m    = newTestManager(Opt{}, s)
+	)
+	hook.Timeout, hook.MaxRetries = "50ms", 1
+	s.hooks = append(s.hooks, hook)
+
+	// Synchronous delivery.
+	start := time.Now()
+	res, err := m.DeliverNow(hook.ID, "", nil, models.SystemWebhookActor)
+	if err != nil {
+		t.Fatalf("error delivering: %v", err)
+	}
+	if res.ErrorClass != models.WebhookErrorTimeout {
+		t.Errorf("expected error class %s, got %s (%s)", models.WebhookErrorTimeout, res.ErrorClass, res.Error)
+	}
+	if res.StatusCode != 0 {
+		t.Errorf("expected no status code, got %d", res.StatusCode)
+	}
+	if d := time.Since(start); d > time.Second*5 {
+		t.Errorf("delivery wasn't cut off at the timeout, took %s", d)
+	}
+
+	// Queued delivery, which is retried.
+	if _, err := m.TriggerWebhook(hook.ID, models.EventWebhookTest, nil, models.SystemWebhookActor); err != nil {
+		t.Fatalf("error triggering: %v", err)
+	}
+	m.processPendingLogs(Pool{Name: "default"}, nil)
+
+	up := s.getUpdates()
+	if len(up) != 1 {
+		t.Fatalf("expected 1 delivery outcome, got %d", len(up))
+	}
+	if up[0].Success || up[0].ErrClass != models.WebhookErrorTimeout {
+		t.Errorf("expected a %s failure, got %+v", models.WebhookErrorTimeout, up[0])
+	}
+	if !up[0].Next.Valid {
+		t.Error("expected the timed out delivery to be retried")
+	}
+}
+
+func TestBlockedReceiver(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer srv.Close()
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	var (
+		hook = newTestHook(1, srv.URL)
+		s    = newFakeStore(hook)
+		m    = newTestManager(Opt{BlockedCIDRs: []*net.IPNet{loopback}}, s)
+	)
+
+	// A delivery to a blocked range fails for good without reaching the receiver.
+	if _, err := m.TriggerWebhook(hook.ID, models.EventWebhookTest, nil, models.SystemWebhookActor); err != nil {
+		t.Fatalf("error triggering: %v", err)
+	}
+	m.processPendingLogs(Pool{Name: "default"}, nil)
+
+	up := s.getUpdates()
+	if len(up) != 1 {
+		t.Fatalf("expected 1 delivery outcome, got %d", len(up))
+	}
+	if up[0].Success || up[0].ErrClass != models.WebhookErrorBlocked {
+		t.Errorf("expected a %s failure, got %+v", models.WebhookErrorBlocked, up[0])
+	}
+	if up[0].Next.Valid {
+		t.Error("expected the blocked delivery not to be retried")
+	}
+	if hits != 0 {
+		t.Errorf("expected the receiver not to be reached, got %d requests", hits)
+	}
+
+	u, _ := url.Parse(srv.URL)
+	if err := m.CheckURL(context.Background(), u); !errors.Is(err, ErrBlockedAddr) {
+		t.Errorf("expected ErrBlockedAddr from CheckURL, got %v", err)
+	}
+
+	// Allowed hosts are exempt.
+	m = newTestManager(Opt{BlockedCIDRs: []*net.IPNet{loopback}, AllowedHosts: []string{u.Host}}, s)
+	if _, err := m.TriggerWebhook(hook.ID, models.EventWebhookTest, nil, models.SystemWebhookActor); err != nil {
+		t.Fatalf("error triggering: %v", err)
+	}
+	m.processPendingLogs(Pool{Name: "default"}, nil)
+	if up := s.getUpdates(); len(up) != 2 || !up[1].Success || hits != 1 {
+		t.Errorf("expected the allowed host to be delivered to, got %+v", up)
+	}
+}
+
+func TestSignatureVectors(t *testing.T) {
+	// Test vectors in the docs.
+	var (
+		secret  = "secret"
+		ts      = "1735725600"
+		payload = []byte(`{"event":"webhook.test"}`)
+	)
+	cases := []struct {
+		name  string
+		alg   string
+		parts []string
+		sig   string
+	}{
+		{"timestamp", models.WebhookHMACSHA256, []string{ts}, "0a5f08dd64861d00d5d412671e813e861b0f997f96eb4340259eeca395c1c52d"},
+		{"path", models.WebhookHMACSHA256, []string{ts, "/hooks/listmonk?src=1"}, "25a4035ec5cd320e0589960e74e82d7c8cef778410cb5cac32fcf3c777ce6442"},
+		{"url", models.WebhookHMACSHA256, []string{ts, "https://example.com/hooks/listmonk?src=1"}, "7daa7e103ff54714b1e0d2c95218951f3f8e1a82a902fa50483bcdc50d473606"},
+		{"expiry", models.WebhookHMACSHA256, []string{ts, "1735725900"}, "4d46c678e7cb50e7dfc8a3ec96b3310790adae1aab68fedd19059323cee68b0a"},
+		{"sha512", models.WebhookHMACSHA512, []string{ts}, "e2b39903de553ed1f57ab46ad63be9e5334860b5221e9f652d94ce0bfafce187a89fa485d2eae51cfd4f6934762ff33e034083de466ce806c9f4a2175bf64070"},
+		{"sha1", models.WebhookHMACSHA1, []string{ts}, "ddc35c885ccfdaf22689f09f43c0c3b97e045130"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := computeHMAC(c.alg, secret, c.parts, payload); got != c.sig {
+				t.Errorf("expected signature %s, got %s", c.sig, got)
+			}
+
+			ok, err := VerifySignature(secret, c.alg+"="+c.sig, c.parts, payload)
+			if err != nil || !ok {
+				t.Errorf("expected the signature to verify, got %v, %v", ok, err)
+			}
+
+			// A signature with another secret or of a tampered body doesn't verify.
+			if ok, _ := VerifySignature("other", c.alg+"="+c.sig, c.parts, payload); ok {
+				t.Error("expected a signature with another secret to fail")
+			}
+			if ok, _ := VerifySignature(secret, c.alg+"="+c.sig, c.parts, []byte(`{"event":"webhook.tset"}`)); ok {
+				t.Error("expected a signature of a tampered body to fail")
+			}
+		})
+	}
+
+	// Malformed signatures.
+	for _, sig := range []string{"0a5f08dd", "md5=0a5f08dd", "sha256=not-hex"} {
+		if _, err := VerifySignature(secret, sig, []string{ts}, payload); err == nil {
+			t.Errorf("expected an error for the signature %s", sig)
+		}
+	}
+}
+
+func TestUnmarshallablePayload(t *testing.T) {
+	for name, data := range map[string]any{
+		"chan": map[string]any{"ch": make(chan int)},
+		"func": map[string]any{"fn": func() {}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var (
+				s = newFakeStore(
+					newTestHook(1, "http://localhost/a", models.EventSubscriberCreated),
+					newTestHook(2, "http://localhost/b", models.EventSubscriberCreated),
+				)
+				m = newTestManager(Opt{}, s)
+			)
+
+			// The event is recorded as a failed log on every subscribed webhook.
+			err := m.Trigger(models.EventSubscriberCreated, data, models.SystemWebhookActor)
+			if !errors.Is(err, ErrPayload) {
+				t.Fatalf("expected ErrPayload, got %v", err)
+			}
+			if len(s.pending) != 0 {
+				t.Errorf("expected no pending logs, got %d", len(s.pending))
+			}
+			if len(s.failed) != 2 {
+				t.Fatalf("expected 2 failed logs, got %d", len(s.failed))
+			}
+			for _, f := range s.failed {
+				if f.ErrClass != models.WebhookErrorPayload || f.Event != models.EventSubscriberCreated {
+					t.Errorf("unexpected failed log: %+v", f)
+				}
+
+				var ev models.WebhookEvent
+				if err := json.Unmarshal(f.Payload, &ev); err != nil || ev.Data != nil {
+					t.Errorf("expected a payload without data, got %s (%v)", f.Payload, err)
+				}
+			}
+
+			// Events for a single webhook are rejected without being queued.
+			if _, err := m.TriggerWebhook(1, models.EventSubscriberCreated, data, models.SystemWebhookActor); !errors.Is(err, ErrPayload) {
+				t.Errorf("expected ErrPayload, got %v", err)
+			}
+			if _, err := m.DeliverNow(1, models.EventSubscriberCreated, data, models.SystemWebhookActor); !errors.Is(err, ErrPayload) {
+				t.Errorf("expected ErrPayload, got %v", err)
+			}
+			if len(s.pending) != 0 {
+				t.Errorf("expected no pending logs, got %d", len(s.pending))
+			}
+		})
+	}
+}
+
+func TestCriticalPool(t *testing.T) {
+	// A bulk receiver that hangs until the test is done, which saturates the default pool.
+	var (
+		done    = make(chan struct{})
+		started = make(chan struct{}, 10)
+	)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-done
+	}))
+	defer slow.Close()
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fast.Close()
+
+	var (
+		bulk     = newTestHook(1, slow.URL, models.EventSubscriberCreated)
+		critical = newTestHook(2, fast.URL, models.EventSubscriberBounced)
+		s        = newFakeStore()
+	)
+	bulk.Timeout = "10s"
+	s.hooks = append(s.hooks, bulk, critical)
+
+	m := newTestManager(Opt{
+		Workers: 1,
+		Pools:   []Pool{{Name: "critical", Workers: 1, Events: []string{models.EventSubscriberBounced}}},
+	}, s)
+	for range 5 {
+		if err := m.Trigger(models.EventSubscriberCreated, map[string]any{}, models.SystemWebhookActor); err != nil {
+			t.Fatalf("error triggering: %v", err)
+		}
+	}
+	go m.Run()
+	defer m.Close()
+	defer close(done)
+
+	select {
+	case <-started:
+	case <-time.After(time.Second * 5):
+		t.Fatal("the bulk events weren't picked up")
+	}
+
+	if err := m.Trigger(models.EventSubscriberBounced, map[string]any{}, models.SystemWebhookActor); err != nil {
+		t.Fatalf("error triggering: %v", err)
+	}
+
+	// The bounce is delivered while the default pool is still stuck on the bulk events.
+	deadline := time.Now().Add(time.Second * 2)
+	for {
+		up := s.getUpdates()
+		if len(up) == 1 && up[0].WebhookID == critical.ID && up[0].Success {
+			break
+		}
+		if len(up) > 0 || time.Now().After(deadline) {
+			t.Fatalf("expected only the critical event to be delivered, got %+v", up)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestStaggeredWorkers(t *testing.T) {
+	const (
+		workers  = 4
+		interval = time.Millisecond * 200
+	)
+
+	m := newTestManager(Opt{Workers: workers, Interval: interval}, newFakeStore())
+	for n := range workers {
+		if d := m.workerPhase(n, workers); d != 0 {
+			t.Errorf("expected no phase without stagger for worker %d, got %s", n, d)
+		}
+	}
+
+	s := newFakeStore()
+	m = newTestManager(Opt{Workers: workers, Interval: interval, Stagger: true}, s)
+	for n := range workers {
+		if d, exp := m.workerPhase(n, workers), interval*time.Duration(n)/workers; d != exp {
+			t.Errorf("expected phase %s for worker %d, got %s", exp, n, d)
+		}
+	}
+
+	// The first polls of the workers are spread over the interval.
+	go m.Run()
+	deadline := time.Now().Add(time.Second * 5)
+	for len(s.getPolls()) < workers && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	m.Close()
+
+	polls := s.getPolls()
+	if len(polls) < workers {
+		t.Fatalf("expected %d polls, got %d", workers, len(polls))
+	}
+	for i := 1; i < workers; i++ {
+		if d := polls[i].Sub(polls[i-1]); d < interval/workers/2 {
+			t.Errorf("expected polls %d and %d to be spread out, got %s apart", i-1, i, d)
+		}
+	}
+}
+
+func TestRequeuedLogAge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var (
+		hook = newTestHook(1, srv.URL)
+		s    = newFakeStore()
+		m    = newTestManager(Opt{}, s)
+	)
+	hook.MaxEventAge, hook.TotalDeadline = "1h", "1h"
+	s.hooks = append(s.hooks, hook)
+
+	// Two logs that were created before the max age and deadline, one of which was
+	// retried manually since.
+	for _, requeued := range []bool{false, true} {
+		if _, err := s.CreateLog(LogRow{WebhookID: hook.ID, Event: models.EventWebhookTest, Payload: json.RawMessage(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+
+		l := &s.pending[len(s.pending)-1]
+		l.CreatedAt = time.Now().Add(-time.Hour * 2)
+		if requeued {
+			l.RequeuedAt = null.TimeFrom(time.Now())
+		}
+	}
+	m.processPendingLogs(Pool{Name: "default"}, nil)
+
+	if len(s.expired) != 1 || s.expired[0] != 1 {
+		t.Errorf("expected only the log that wasn't retried to expire, got %v", s.expired)
+	}
+	if up := s.getUpdates(); len(up) != 1 || up[0].ID != 2 || !up[0].Success {
+		t.Errorf("expected the retried log to be delivered, got %+v", up)
+	}
+}
+
+func TestTestDeliveryFailures(t *testing.T) {
+	var code = http.StatusInternalServerError
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	var (
+		hook = newTestHook(1, srv.URL)
+		s    = newFakeStore(hook)
+		m    = newTestManager(Opt{BreakerThreshold: 1}, s)
+	)
+
+	// Failed and successful test deliveries don't touch the failure count or the breaker.
+	for _, c := range []int{http.StatusInternalServerError, http.StatusOK} {
+		code = c
+		if _, err := m.TriggerTest(hook.ID, "", nil, models.SystemWebhookActor); err != nil {
+			t.Fatalf("error triggering: %v", err)
+		}
+		m.processPendingLogs(Pool{Name: "default"}, nil)
+	}
+	if len(s.getUpdates()) != 2 {
+		t.Fatalf("expected 2 delivery outcomes, got %d", len(s.getUpdates()))
+	}
+	if s.failures[hook.ID] != 0 || s.resets[hook.ID] != 0 {
+		t.Errorf("expected no failure accounting, got %d failures and %d resets", s.failures[hook.ID], s.resets[hook.ID])
+	}
+	if b := m.Breaker(hook.ID); b.State != models.WebhookBreakerClosed || b.Failures != 0 {
+		t.Errorf("expected a closed breaker without failures, got %+v", b)
+	}
+
+	// Real deliveries are counted.
+	code = http.StatusInternalServerError
+	if _, err := m.TriggerWebhook(hook.ID, models.EventWebhookTest, nil, models.SystemWebhookActor); err != nil {
+		t.Fatalf("error triggering: %v", err)
+	}
+	m.processPendingLogs(Pool{Name: "default"}, nil)
+	if s.failures[hook.ID] != 1 {
+		t.Errorf("expected 1 failure, got %d", s.failures[hook.ID])
+	}
+	if b := m.Breaker(hook.ID); b.State != models.WebhookBreakerOpen {
+		t.Errorf("expected an open breaker, got %+v", b)
+	}
+}
+
+func TestTriggerSpread(t *testing.T) {
+	const (
+		event  = "custom.order_placed"
+		spread = time.Second * 10
+		jitter = 0.2
+		total  = 5
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	s := newFakeStore()
+	for i := range total {
+		s.hooks = append(s.hooks, newTestHook(i+1, srv.URL, event, models.EventSubscriberCreated))
+	}
+	m := newTestManager(Opt{TriggerSpread: spread, RetryJitter: jitter}, s)
+
+	// The deliveries of a custom event are spread out over the webhooks in order.
+	now := time.Now()
+	if err := m.Trigger(event, map[string]any{}, models.SystemWebhookActor); err != nil {
+		t.Fatalf("error triggering: %v", err)
+	}
+	if len(s.pending) != total {
+		t.Fatalf("expected %d queued logs, got %d", total, len(s.pending))
+	}
+
+	var last time.Duration
+	for i, l := range s.pending {
+		d := l.NextRetryAt.Time.Sub(now)
+		if i == 0 {
+			if d > time.Second {
+				t.Errorf("expected the first delivery to be due right away, got %v", d)
+			}
+			continue
+		}
+		if d <= last || d > time.Duration(float64(spread)*(1+jitter)) {
+			t.Errorf("expected delivery %d to be due after %v and within %v, got %v", i, last, spread, d)
+		}
+		last = d
+	}
+
+	m.processPendingLogs(Pool{Name: "default"}, nil)
+	if n := len(s.getUpdates()); n != 1 {
+		t.Errorf("expected only the first delivery to be made right away, got %d", n)
+	}
+
+	// Built-in events aren't spread.
+	s.pending = nil
+	if err := m.Trigger(models.EventSubscriberCreated, map[string]any{}, models.SystemWebhookActor); err != nil {
+		t.Fatalf("error triggering: %v", err)
+	}
+	for _, l := range s.pending {
+		if d := time.Until(l.NextRetryAt.Time); d > time.Second {
+			t.Errorf("expected built-in events to be due right away, got %v", d)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for _, c := range []struct {
+		strategy string
+		interval string
+		attempts []int
+		exp      []time.Duration
+	}{
+		// The default interval.
+		{models.WebhookBackoffFixed, "", []int{1, 2, 5, 50}, []time.Duration{
+			retryBackoff, retryBackoff, retryBackoff, retryBackoff}},
+		{models.WebhookBackoffLinear, "", []int{1, 2, 5, 50}, []time.Duration{
+			retryBackoff, retryBackoff * 2, retryBackoff * 5, time.Minute * 25}},
+		{models.WebhookBackoffExponential, "", []int{1, 2, 5, 50}, []time.Duration{
+			retryBackoff, retryBackoff * 2, retryBackoff * 16, maxRetryBackoff}},
+
+		// A custom interval. Delays are capped at maxRetryBackoff.
+		{models.WebhookBackoffFixed, "3h", []int{1, 2, 5, 50}, []time.Duration{
+			maxRetryBackoff, maxRetryBackoff, maxRetryBackoff, maxRetryBackoff}},
+		{models.WebhookBackoffLinear, "10m", []int{1, 2, 5, 50}, []time.Duration{
+			time.Minute * 10, time.Minute * 20, time.Minute * 50, maxRetryBackoff}},
+		{models.WebhookBackoffExponential, "10s", []int{1, 2, 5, 50}, []time.Duration{
+			time.Second * 10, time.Second * 20, time.Second * 160, maxRetryBackoff}},
+
+		// Invalid intervals and intervals under a second fall back to the default.
+		{models.WebhookBackoffFixed, "abc", []int{1, 5}, []time.Duration{retryBackoff, retryBackoff}},
+		{models.WebhookBackoffLinear, "500ms", []int{1, 5}, []time.Duration{retryBackoff, retryBackoff * 5}},
+		{models.WebhookBackoffExponential, "-1m", []int{1, 5}, []time.Duration{retryBackoff, retryBackoff * 16}},
+	} {
+		w := models.Webhook{BackoffStrategy: c.strategy, RetryInterval: c.interval}
+		for i, a := range c.attempts {
+			if d := retryDelay(w, a); d != c.exp[i] {
+				t.Errorf("%s (%q), attempt %d: expected %v, got %v", c.strategy, c.interval, a, c.exp[i], d)
+			}
+		}
+	}
+}
+
+func TestFilterExpression(t *testing.T) {
+	hook := newTestHook(1, "http://example.com", models.EventSubscriberCreated)
+	hook.FilterExpression = `subscriber.status == "blocklisted" && subscriber.attribs.plan in ["pro", "team"]`
+
+	for name, c := range map[string]struct {
+		data  map[string]any
+		match bool
+	}{
+		"match":     {map[string]any{"subscriber": map[string]any{"status": "blocklisted", "attribs": map[string]any{"plan": "pro"}}}, true},
+		"status":    {map[string]any{"subscriber": map[string]any{"status": "enabled", "attribs": map[string]any{"plan": "pro"}}}, false},
+		"attrib":    {map[string]any{"subscriber": map[string]any{"status": "blocklisted", "attribs": map[string]any{"plan": "free"}}}, false},
+		"no attrib": {map[string]any{"subscriber": map[string]any{"status": "blocklisted", "attribs": map[string]any{}}}, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var (
+				s = newFakeStore(hook)
+				m = newTestManager(Opt{}, s)
+			)
+			if err := m.Trigger(models.EventSubscriberCreated, c.data, models.SystemWebhookActor); err != nil {
+				t.Fatalf("error triggering: %v", err)
+			}
+
+			if n := len(s.pending); c.match && n != 1 {
+				t.Errorf("expected the matching event to be queued, got %d logs", n)
+			} else if !c.match && n != 0 {
+				t.Errorf("expected the event not to be queued, got %d logs", n)
+			}
+		})
+	}
+}
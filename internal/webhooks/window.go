@@ -0,0 +1,146 @@
+package webhooks
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RetryWindow is a daily window of time, optionally on certain weekdays, in a timezone,
+// within which failed deliveries are retried. eg: "mon-fri 09:00-17:00".
+// A window whose end is before its start, eg: "22:00-06:00", crosses midnight.
+type RetryWindow struct {
+	days [7]bool
+
+	// Minutes of the day.
+	start int
+	end   int
+	loc   *time.Location
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseRetryWindow parses a retry window spec of the form "[days] HH:MM-HH:MM" where days
+// is an optional comma separated list of weekdays or weekday ranges, eg: "mon-fri" or
+// "mon,wed,fri". tz is an IANA timezone, eg: "Europe/Berlin". An empty tz is UTC.
+func ParseRetryWindow(spec, tz string) (*RetryWindow, error) {
+	loc := time.UTC
+	if tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone: %s", tz)
+		}
+		loc = l
+	}
+
+	w := &RetryWindow{loc: loc}
+
+	f := strings.Fields(strings.ToLower(spec))
+	switch len(f) {
+	case 1:
+		for i := range w.days {
+			w.days[i] = true
+		}
+	case 2:
+		if err := w.parseDays(f[0]); err != nil {
+			return nil, err
+		}
+		f = f[1:]
+	default:
+		return nil, fmt.Errorf("invalid retry window: %s", spec)
+	}
+
+	from, to, ok := strings.Cut(f[0], "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid retry window: %s", spec)
+	}
+
+	var err error
+	if w.start, err = parseClock(from); err != nil {
+		return nil, err
+	}
+	if w.end, err = parseClock(to); err != nil {
+		return nil, err
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("invalid retry window: %s", spec)
+	}
+
+	return w, nil
+}
+
+// Next returns t if it falls within the window, or the start of the next window.
+func (w *RetryWindow) Next(t time.Time) time.Time {
+	t = t.In(w.loc)
+
+	// Start from the previous day as its window may cross midnight into today.
+	for i := -1; i <= 7; i++ {
+		d := time.Date(t.Year(), t.Month(), t.Day()+i, 0, 0, 0, 0, w.loc)
+		if !w.days[d.Weekday()] {
+			continue
+		}
+
+		start := time.Date(d.Year(), d.Month(), d.Day(), w.start/60, w.start%60, 0, 0, w.loc)
+		end := time.Date(d.Year(), d.Month(), d.Day(), w.end/60, w.end%60, 0, 0, w.loc)
+		if w.end < w.start {
+			end = end.AddDate(0, 0, 1)
+		}
+
+		if t.Before(start) {
+			return start
+		}
+		if t.Before(end) {
+			return t
+		}
+	}
+
+	return t
+}
+
+// parseDays parses a comma separated list of weekdays or weekday ranges.
+func (w *RetryWindow) parseDays(s string) error {
+	for _, p := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(p, "-")
+		a, ok := weekdays[from]
+		if !ok {
+			return fmt.Errorf("invalid weekday: %s", from)
+		}
+		if !isRange {
+			w.days[a] = true
+			continue
+		}
+
+		b, ok := weekdays[to]
+		if !ok {
+			return fmt.Errorf("invalid weekday: %s", to)
+		}
+
+		// Ranges can wrap around the week, eg: sat-sun.
+		for d := a; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == b {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseClock parses an HH:MM time of the day into minutes.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time: %s", s)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
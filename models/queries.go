@@ -64,6 +64,7 @@ type Queries struct {
 	GetCampaign           *sqlx.Stmt `query:"get-campaign"`
 	GetCampaignForPreview *sqlx.Stmt `query:"get-campaign-for-preview"`
 	GetCampaignStats      *sqlx.Stmt `query:"get-campaign-stats"`
+	GetCampaignLists      *sqlx.Stmt `query:"get-campaign-lists"`
 	GetCampaignStatus     *sqlx.Stmt `query:"get-campaign-status"`
 	GetArchivedCampaigns  *sqlx.Stmt `query:"get-archived-campaigns"`
 	CampaignHasLists      *sqlx.Stmt `query:"campaign-has-lists"`
@@ -116,6 +117,42 @@ type Queries struct {
 	DeleteBouncesBySubscriber   *sqlx.Stmt `query:"delete-bounces-by-subscriber"`
 	GetDBInfo                   string     `query:"get-db-info"`
 
+	GetWebhooks               *sqlx.Stmt `query:"get-webhooks"`
+	GetWebhooksByEvent        *sqlx.Stmt `query:"get-webhooks-by-event"`
+	CreateWebhook             *sqlx.Stmt `query:"create-webhook"`
+	UpdateWebhook             *sqlx.Stmt `query:"update-webhook"`
+	RotateWebhookSecret       *sqlx.Stmt `query:"rotate-webhook-secret"`
+	FinalizeWebhookSecret     *sqlx.Stmt `query:"finalize-webhook-secret-rotation"`
+	UpdateWebhooksStatus      *sqlx.Stmt `query:"update-webhooks-status"`
+	DeleteWebhooks            *sqlx.Stmt `query:"delete-webhooks"`
+	CreateWebhookAudit        *sqlx.Stmt `query:"create-webhook-audit"`
+	GetWebhookHistory         *sqlx.Stmt `query:"get-webhook-history"`
+	GetWebhookStats           *sqlx.Stmt `query:"get-webhook-stats"`
+	CreateWebhookLog          *sqlx.Stmt `query:"create-webhook-log"`
+	CreateWebhookLogs         *sqlx.Stmt `query:"create-webhook-logs"`
+	CreateFailedWebhookLog    *sqlx.Stmt `query:"create-failed-webhook-log"`
+	GetPendingWebhookLogs     *sqlx.Stmt `query:"get-pending-webhook-logs"`
+	UpdateWebhookLogSuccess   *sqlx.Stmt `query:"update-webhook-log-success"`
+	SetWebhookLogConfirmToken *sqlx.Stmt `query:"set-webhook-log-confirm-token"`
+	ConfirmWebhookLog         *sqlx.Stmt `query:"confirm-webhook-log"`
+	UpdateWebhookLogFailed    *sqlx.Stmt `query:"update-webhook-log-failed"`
+	UpdateWebhookLogExpired   *sqlx.Stmt `query:"update-webhook-log-expired"`
+	DeferWebhookLog           *sqlx.Stmt `query:"defer-webhook-log"`
+	AppendWebhookLogAttempt   *sqlx.Stmt `query:"append-webhook-log-attempt"`
+	RecordWebhookFailure      *sqlx.Stmt `query:"record-webhook-failure"`
+	ResetWebhookFailures      *sqlx.Stmt `query:"reset-webhook-failures"`
+	QueryWebhookLogs          string     `query:"query-webhook-logs"`
+	ExportWebhookLogs         *sqlx.Stmt `query:"export-webhook-logs"`
+	GetWebhookLogErrors       *sqlx.Stmt `query:"get-webhook-log-errors"`
+	GetDeadLetterLogs         *sqlx.Stmt `query:"get-dead-letter-logs"`
+	ResubmitDeadLetters       *sqlx.Stmt `query:"resubmit-dead-letters"`
+	GetWebhookDigest          *sqlx.Stmt `query:"get-webhook-digest"`
+	RetryWebhookLog           *sqlx.Stmt `query:"retry-webhook-log"`
+	RetryWebhookLogs          *sqlx.Stmt `query:"retry-webhook-logs"`
+	DeleteWebhookLogs         *sqlx.Stmt `query:"delete-webhook-logs"`
+	DeleteWebhookLogsByFilter *sqlx.Stmt `query:"delete-webhook-logs-by-filter"`
+	PruneWebhookLogs          *sqlx.Stmt `query:"prune-webhook-logs"`
+
 	CreateUser        *sqlx.Stmt `query:"create-user"`
 	UpdateUser        *sqlx.Stmt `query:"update-user"`
 	UpdateUserProfile *sqlx.Stmt `query:"update-user-profile"`
@@ -189,3 +226,22 @@ func (q *Queries) ExecSubQueryTpl(searchStr, queryExp, baseQueryTpl string, list
 	}
 	return nil
 }
+
+// SelectSubQueryTpl is like ExecSubQueryTpl, but scans the rows that the combined
+// query returns into dest, eg: the subscribers that were blocklisted.
+func (q *Queries) SelectSubQueryTpl(dest any, searchStr, queryExp, baseQueryTpl string, listIDs []int, db *sqlx.DB, subStatus string, args ...any) error {
+	// Perform a dry run.
+	filterExp, err := q.compileSubscriberQueryTpl(searchStr, queryExp, db, subStatus)
+	if err != nil {
+		return err
+	}
+
+	if len(listIDs) == 0 {
+		listIDs = []int{}
+	}
+
+	stmt := strings.ReplaceAll(baseQueryTpl, "%query%", filterExp)
+	a := append([]any{false, pq.Array(listIDs), subStatus, searchStr}, args...)
+
+	return db.Select(dest, stmt, a...)
+}
@@ -109,6 +109,13 @@ type Subscription struct {
 	Meta                  json.RawMessage `db:"meta" json:"meta"`
 }
 
+// SubscriptionStatus represents the status of a subscriber's subscription to a list.
+type SubscriptionStatus struct {
+	SubscriberID int    `db:"subscriber_id" json:"subscriber_id"`
+	ListID       int    `db:"list_id" json:"list_id"`
+	Status       string `db:"status" json:"status"`
+}
+
 // SubscriberExport represents a subscriber record that is exported to raw data.
 type SubscriberExport struct {
 	Base
@@ -0,0 +1,587 @@
+package models
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+const (
+	WebhookStatusEnabled  = "enabled"
+	WebhookStatusDisabled = "disabled"
+
+	// Events are still queued for paused webhooks, but aren't delivered
+	// until they're resumed.
+	WebhookStatusPaused = "paused"
+
+	WebhookAuthTypeNone  = "none"
+	WebhookAuthTypeBasic = "basic"
+	WebhookAuthTypeHMAC  = "hmac"
+
+	// The timestamp that's signed in HMAC signatures. 'send' is the time of
+	// each delivery attempt and 'created' is the time the event was queued,
+	// which remains the same across retries.
+	WebhookHMACTimestampSend    = "send"
+	WebhookHMACTimestampCreated = "created"
+
+	// Part of the request URL that's included in the HMAC signature along
+	// with the timestamp and the body, binding the signature to an endpoint.
+	WebhookHMACSignURLNone = "none"
+	WebhookHMACSignURLPath = "path"
+	WebhookHMACSignURLFull = "url"
+
+	// Hash algorithms for HMAC signatures. sha1 is only for legacy receivers.
+	WebhookHMACSHA256 = "sha256"
+	WebhookHMACSHA512 = "sha512"
+	WebhookHMACSHA1   = "sha1"
+
+	// Naming of the JSON keys in payloads. 'camel' camelCases the envelope's keys,
+	// eg: message_id => messageId, and 'camel_nested' camelCases all keys.
+	WebhookFieldNamingSnake       = "snake"
+	WebhookFieldNamingCamel       = "camel"
+	WebhookFieldNamingCamelNested = "camel_nested"
+
+	// Formats of the payloads. 'slack' sends events as Slack messages ({text, blocks})
+	// for posting to Slack incoming webhooks, and 'discord', as Discord messages with
+	// embeds for posting to Discord webhooks.
+	WebhookPayloadFormatListmonk = "listmonk"
+	WebhookPayloadFormatSlack    = "slack"
+	WebhookPayloadFormatDiscord  = "discord"
+
+	// Strategies for the delays between retries of failed deliveries, in units of
+	// the webhook's retry interval: interval * 2^(attempt-1), interval * attempt,
+	// or interval.
+	WebhookBackoffExponential = "exponential"
+	WebhookBackoffLinear      = "linear"
+	WebhookBackoffFixed       = "fixed"
+
+	// Types of actors that trigger webhook events. 'system' is for events that
+	// aren't triggered by a request, eg: bounces and campaign status changes by the
+	// campaign manager.
+	WebhookActorUser       = "user"
+	WebhookActorSubscriber = "subscriber"
+	WebhookActorSystem     = "system"
+
+	// Actions recorded in the webhook audit trail.
+	WebhookAuditCreate         = "create"
+	WebhookAuditUpdate         = "update"
+	WebhookAuditDelete         = "delete"
+	WebhookAuditTest           = "test"
+	WebhookAuditRotateSecret   = "rotate_secret"
+	WebhookAuditFinalizeSecret = "finalize_secret"
+	WebhookAuditPause          = "pause"
+	WebhookAuditResume         = "resume"
+	WebhookAuditRetryLogs      = "retry_logs"
+	WebhookAuditResubmitLogs   = "resubmit_dead_letters"
+
+	// Content type of the merge patches that are sent for update events
+	// to webhooks that use the PATCH method.
+	WebhookContentTypeMergePatch = "application/merge-patch+json"
+
+	// How events with empty (nil) data are sent. 'null' sends the data as null,
+	// 'object' sends it as {}, and 'skip' doesn't deliver the event at all.
+	WebhookEmptyDataNull   = "null"
+	WebhookEmptyDataObject = "object"
+	WebhookEmptyDataSkip   = "skip"
+
+	// Classes of delivery errors that are recorded on logs.
+	WebhookErrorTimeout    = "timeout"
+	WebhookErrorConnection = "connection"
+	WebhookErrorDNS        = "dns"
+	WebhookErrorTLS        = "tls"
+	WebhookErrorHTTP       = "http"
+	WebhookErrorResponse   = "response"
+	WebhookErrorRequest    = "request"
+	WebhookErrorPayload    = "payload"
+	WebhookErrorBlocked    = "blocked"
+
+	// The receiver accepted the delivery but didn't confirm it within the webhook's
+	// confirm_window.
+	WebhookErrorUnconfirmed = "unconfirmed"
+
+	// States of a webhook's delivery circuit breaker. An open circuit defers deliveries
+	// until its cooldown passes, after which a single probe delivery is attempted (half_open).
+	WebhookBreakerClosed   = "closed"
+	WebhookBreakerOpen     = "open"
+	WebhookBreakerHalfOpen = "half_open"
+
+	// Reasons for subscribers being blocklisted in subscriber.blocklisted events: by a
+	// user, by the bounce policy, or by the subscriber on unsubscribing.
+	WebhookBlocklistManual      = "manual"
+	WebhookBlocklistBounce      = "bounce"
+	WebhookBlocklistUnsubscribe = "unsubscribe"
+
+	// Scopes of subscriber.unsubscribed events: an opt-out of specific lists, or
+	// a full unsubscribe from every list the subscriber is on.
+	WebhookUnsubscribeScopeLists = "lists"
+	WebhookUnsubscribeScopeAll   = "all"
+
+	WebhookLogStatusPending = "pending"
+	WebhookLogStatusSuccess = "success"
+	WebhookLogStatusFailed  = "failed"
+	WebhookLogStatusExpired = "expired"
+)
+
+// Webhook events.
+const (
+	EventSubscriberCreated         = "subscriber.created"
+	EventSubscriberUpdated         = "subscriber.updated"
+	EventSubscriberDeleted         = "subscriber.deleted"
+	EventSubscriberAddedToList     = "subscriber.added_to_list"
+	EventSubscriberRemovedFromList = "subscriber.removed_from_list"
+	EventSubscriberUnsubscribed    = "subscriber.unsubscribed"
+	EventSubscriberBounced         = "subscriber.bounced"
+	EventSubscriberReactivated     = "subscriber.reactivated"
+	EventSubscriberDataRequested   = "subscriber.data_requested"
+	EventSubscriberBlocklisted     = "subscriber.blocklisted"
+
+	EventCampaignCreated   = "campaign.created"
+	EventCampaignUpdated   = "campaign.updated"
+	EventCampaignStarted   = "campaign.started"
+	EventCampaignProgress  = "campaign.progress"
+	EventCampaignPaused    = "campaign.paused"
+	EventCampaignCancelled = "campaign.cancelled"
+	EventCampaignFinished  = "campaign.finished"
+	EventCampaignArchived  = "campaign.archived"
+
+	EventListCreated = "list.created"
+	EventListUpdated = "list.updated"
+	EventListDeleted = "list.deleted"
+
+	EventTemplateUpdated = "template.updated"
+
+	// EventWebhookTest is sent to a single webhook on a test delivery.
+	// Webhooks can't subscribe to it.
+	EventWebhookTest = "webhook.test"
+
+	// EventWebhookDigest is the periodic delivery health digest that's sent
+	// to the webhook configured for it. Webhooks can't subscribe to it.
+	EventWebhookDigest = "webhook.digest"
+
+	// Prefix of user-defined events that are triggered over the API,
+	// eg: custom.order_placed.
+	EventCustomPrefix = "custom."
+)
+
+var reCustomEvent = regexp.MustCompile(`^custom\.[a-z0-9_\-.]{1,100}$`)
+
+// IsCustomWebhookEvent checks whether an event is a valid user-defined
+// event name, eg: custom.order_placed.
+func IsCustomWebhookEvent(event string) bool {
+	return reCustomEvent.MatchString(event)
+}
+
+// WebhookEventCategory returns the category of an event, ie: the part
+// before the dot, eg: 'subscriber' for 'subscriber.created'.
+func WebhookEventCategory(event string) string {
+	cat, _, _ := strings.Cut(event, ".")
+	return cat
+}
+
+// WebhookCategoryEvents returns the subscribable events in the given category.
+func WebhookCategoryEvents(category string) []string {
+	var out []string
+	for _, e := range AllWebhookEvents() {
+		if WebhookEventCategory(e) == category {
+			out = append(out, e)
+		}
+	}
+
+	return out
+}
+
+// WebhookPayloadVersions is the list of supported webhook payload schema
+// versions. The last one is the latest.
+var WebhookPayloadVersions = []string{"1"}
+
+// UnknownWebhookEvents returns the events that aren't in AllWebhookEvents() and aren't
+// custom events, eg: events that were removed in an upgrade that webhooks are still
+// subscribed to.
+func UnknownWebhookEvents(events []string) []string {
+	var (
+		all = AllWebhookEvents()
+		out []string
+	)
+	for _, e := range events {
+		if !slices.Contains(all, e) && !IsCustomWebhookEvent(e) {
+			out = append(out, e)
+		}
+	}
+
+	return out
+}
+
+// AllWebhookEvents returns the list of events that webhooks can subscribe to.
+func AllWebhookEvents() []string {
+	return []string{
+		EventSubscriberCreated,
+		EventSubscriberUpdated,
+		EventSubscriberDeleted,
+		EventSubscriberAddedToList,
+		EventSubscriberRemovedFromList,
+		EventSubscriberUnsubscribed,
+		EventSubscriberBounced,
+		EventSubscriberReactivated,
+		EventSubscriberDataRequested,
+		EventSubscriberBlocklisted,
+		EventCampaignCreated,
+		EventCampaignUpdated,
+		EventCampaignStarted,
+		EventCampaignProgress,
+		EventCampaignPaused,
+		EventCampaignCancelled,
+		EventCampaignFinished,
+		EventCampaignArchived,
+		EventListCreated,
+		EventListUpdated,
+		EventListDeleted,
+		EventTemplateUpdated,
+	}
+}
+
+// Webhook represents an outgoing webhook that's posted to on subscribed events.
+type Webhook struct {
+	Base
+
+	UUID               string         `db:"uuid" json:"uuid"`
+	Name               string         `db:"name" json:"name"`
+	URL                string         `db:"url" json:"url"`
+	Status             string         `db:"status" json:"status"`
+	Events             pq.StringArray `db:"events" json:"events"`
+	AuthType           string         `db:"auth_type" json:"auth_type"`
+	AuthBasicUser      string         `db:"auth_basic_user" json:"auth_basic_user"`
+	AuthBasicPass      string         `db:"auth_basic_pass" json:"auth_basic_pass,omitempty"`
+	AuthHMACSecret     string         `db:"auth_hmac_secret" json:"auth_hmac_secret,omitempty"`
+	MaxRetries         int            `db:"max_retries" json:"max_retries"`
+	Timeout            string         `db:"timeout" json:"timeout"`
+	AuthHMACTimestamp  string         `db:"auth_hmac_timestamp" json:"auth_hmac_timestamp"`
+	MaxEventAge        string         `db:"max_event_age" json:"max_event_age"`
+	Debug              bool           `db:"debug" json:"debug"`
+	HTTPMethod         string         `db:"http_method" json:"http_method"`
+	PayloadVersion     string         `db:"payload_version" json:"payload_version"`
+	Charset            string         `db:"charset" json:"charset"`
+	MaxInflightRetries int            `db:"max_inflight_retries" json:"max_inflight_retries"`
+	EmptyData          string         `db:"empty_data" json:"empty_data"`
+	DisableKeepAlive   bool           `db:"disable_keep_alive" json:"disable_keep_alive"`
+	SuccessBodyRegex   string         `db:"success_body_regex" json:"success_body_regex"`
+	SuccessBodyJSON    string         `db:"success_body_json" json:"success_body_json"`
+	AuthHMACSignURL    string         `db:"auth_hmac_sign_url" json:"auth_hmac_sign_url"`
+	AuthBasicHeader    string         `db:"auth_basic_header" json:"auth_basic_header"`
+	CABundle           string         `db:"ca_bundle" json:"ca_bundle"`
+	InsecureSkipVerify bool           `db:"insecure_skip_verify" json:"insecure_skip_verify"`
+	ContentType        string         `db:"content_type" json:"content_type"`
+	PayloadRootKey     string         `db:"payload_root_key" json:"payload_root_key"`
+	Headers            WebhookHeaders `db:"headers" json:"headers"`
+	RetryWindow        string         `db:"retry_window" json:"retry_window"`
+	RetryWindowTZ      string         `db:"retry_window_tz" json:"retry_window_tz"`
+	AuthHMACTTL        string         `db:"auth_hmac_ttl" json:"auth_hmac_ttl"`
+	HMACAlgorithm      string         `db:"hmac_algorithm" json:"hmac_algorithm"`
+	FieldNaming        string         `db:"field_naming" json:"field_naming"`
+	BackoffStrategy    string         `db:"backoff_strategy" json:"backoff_strategy"`
+	RetryInterval      string         `db:"retry_interval" json:"retry_interval"`
+	FailureThreshold   int            `db:"failure_threshold" json:"failure_threshold"`
+	MaxResponseBody    int            `db:"max_response_body" json:"max_response_body"`
+	Compress           bool           `db:"compress" json:"compress"`
+	Weight             int            `db:"weight" json:"weight"`
+	TotalDeadline      string         `db:"total_deadline" json:"total_deadline"`
+	FilterExpression   string         `db:"filter_expression" json:"filter_expression"`
+	ConfirmWindow      string         `db:"confirm_window" json:"confirm_window"`
+	PayloadFormat      string         `db:"payload_format" json:"payload_format"`
+	SignatureTolerance string         `db:"signature_tolerance" json:"signature_tolerance"`
+
+	// Lists that the webhook is scoped to. Events that carry lists are only
+	// delivered if they're about one of these. Empty is unscoped.
+	ListIDs pq.Int64Array `db:"list_ids" json:"list_ids"`
+
+	// Counter for the per-webhook log sequence.
+	LogSequence int64 `db:"log_sequence" json:"-"`
+
+	// Number of consecutive permanently failed deliveries, which is reset by a
+	// successful delivery or by re-enabling the webhook.
+	ConsecutiveFailures int `db:"consecutive_failures" json:"consecutive_failures"`
+
+	// Error of the most recent failed delivery attempt.
+	LastError   null.String `db:"last_error" json:"last_error"`
+	LastErrorAt null.Time   `db:"last_error_at" json:"last_error_at"`
+
+	// Previous HMAC secret of a rotation that's in progress. Deliveries are signed with
+	// it as well as the current secret until it expires or the rotation is finalized.
+	AuthHMACSecretPrev    string    `db:"auth_hmac_secret_prev" json:"auth_hmac_secret_prev,omitempty"`
+	AuthHMACRotatedAt     null.Time `db:"auth_hmac_rotated_at" json:"auth_hmac_rotated_at"`
+	AuthHMACPrevExpiresAt null.Time `db:"auth_hmac_prev_expires_at" json:"auth_hmac_prev_expires_at"`
+
+	// State of the HMAC secret rotation, filled post-retrieval in place of the secrets.
+	SecretRotation *WebhookSecretRotation `db:"-" json:"secret_rotation,omitempty"`
+
+	// Warnings about insecure settings, eg: insecure_skip_verify, filled post-retrieval.
+	Warnings []string `db:"-" json:"warnings,omitempty"`
+
+	// Pseudofield for getting the total number of webhooks
+	// in searches and queries.
+	Total int `db:"total" json:"-"`
+}
+
+// WebhookSecretRotation is the state of a webhook's HMAC secret rotation.
+type WebhookSecretRotation struct {
+	// Whether the current (primary) secret and the previous (secondary)
+	// secret of a rotation that's in progress are set.
+	Primary   bool      `json:"primary"`
+	Secondary bool      `json:"secondary"`
+	StartedAt null.Time `json:"started_at"`
+	ExpiresAt null.Time `json:"expires_at"`
+}
+
+// PrevHMACSecret returns the previous HMAC secret of a rotation that's in progress,
+// or an empty string if there's none or it has expired.
+func (w Webhook) PrevHMACSecret(now time.Time) string {
+	if w.AuthHMACPrevExpiresAt.Valid && !now.Before(w.AuthHMACPrevExpiresAt.Time) {
+		return ""
+	}
+	return w.AuthHMACSecretPrev
+}
+
+// WebhookAudit represents a change to a webhook's configuration.
+type WebhookAudit struct {
+	ID        int64  `db:"id" json:"id"`
+	WebhookID int    `db:"webhook_id" json:"webhook_id"`
+	Action    string `db:"action" json:"action"`
+
+	// Changed fields as {"field": {"old": .., "new": ..}}. Secrets are redacted.
+	// For test deliveries, it's the {"log_id": ..} of the delivery.
+	Changes   json.RawMessage `db:"changes" json:"changes"`
+	UserID    null.Int        `db:"user_id" json:"user_id"`
+	UserName  string          `db:"user_name" json:"user_name"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+
+	// Pseudofield for getting the total number of audit entries
+	// in searches and queries.
+	Total int `db:"total" json:"-"`
+}
+
+// WebhookStats represents the delivery backlog of a webhook.
+type WebhookStats struct {
+	ID     int    `db:"id" json:"id"`
+	Name   string `db:"name" json:"name"`
+	Status string `db:"status" json:"status"`
+
+	// Number of undelivered logs and the ones among them that are being retried.
+	Pending  int `db:"pending" json:"pending"`
+	Retrying int `db:"retrying" json:"retrying"`
+
+	// Creation time and age (in seconds) of the oldest undelivered log.
+	OldestPendingAt  null.Time `db:"oldest_pending_at" json:"oldest_pending_at"`
+	OldestPendingAge int64     `db:"oldest_pending_age" json:"oldest_pending_age"`
+}
+
+// WebhookLog represents a single webhook delivery and its attempts.
+type WebhookLog struct {
+	ID             int         `db:"id" json:"id"`
+	WebhookID      int         `db:"webhook_id" json:"webhook_id"`
+	WebhookName    null.String `db:"webhook_name" json:"webhook_name"`
+	Event          string      `db:"event" json:"event"`
+	Status         string      `db:"status" json:"status"`
+	IdempotencyKey string      `db:"idempotency_key" json:"idempotency_key"`
+	MessageID      string      `db:"message_id" json:"message_id"`
+	PayloadVersion string      `db:"payload_version" json:"payload_version"`
+
+	// URL that the log was last delivered to, resolved from the webhook's URL template.
+	URL string `db:"url" json:"url"`
+
+	// Test delivery from the webhook test endpoint.
+	Test     bool            `db:"test" json:"test"`
+	Sequence int64           `db:"sequence" json:"sequence"`
+	Payload  json.RawMessage `db:"payload" json:"payload"`
+
+	// Gzip compressed payload, if the payload is stored compressed. See UnpackPayload().
+	PayloadGZ []byte `db:"payload_gz" json:"-"`
+
+	Attempts     int    `db:"attempts" json:"attempts"`
+	ResponseCode int    `db:"response_code" json:"response_code"`
+	ResponseBody string `db:"response_body" json:"response_body"`
+
+	// Hex SHA-256 of the full response body, and whether the recorded body is
+	// truncated (ie: the response was larger than the webhook's max_response_body).
+	ResponseHash      string `db:"response_hash" json:"response_hash"`
+	ResponseTruncated bool   `db:"response_truncated" json:"response_truncated"`
+
+	// Failed attempts ([]WebhookAttempt), if the attempt history is enabled.
+	AttemptHistory json.RawMessage `db:"attempt_history" json:"attempt_history"`
+
+	Error       string    `db:"error" json:"error"`
+	ErrorClass  string    `db:"error_class" json:"error_class"`
+	NextRetryAt null.Time `db:"next_retry_at" json:"next_retry_at"`
+
+	// For webhooks with a confirm_window, the secret token in the log's confirmation
+	// URL, the time by which a delivery has to be confirmed, and the time of confirmation.
+	ConfirmToken string    `db:"confirm_token" json:"-"`
+	ConfirmBy    null.Time `db:"confirm_by" json:"confirm_by"`
+	ConfirmedAt  null.Time `db:"confirmed_at" json:"confirmed_at"`
+
+	// Time at which the log was last retried or resubmitted manually, from which
+	// its webhook's max_event_age and total_deadline count instead of CreatedAt.
+	RequeuedAt null.Time `db:"requeued_at" json:"requeued_at"`
+
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+
+	// Pseudofield for getting the total number of logs
+	// in searches and queries.
+	Total int `db:"total" json:"-"`
+}
+
+// WebhookAttempt represents a failed delivery attempt in a log's attempt history.
+type WebhookAttempt struct {
+	Attempt      int       `json:"attempt"`
+	ResponseCode int       `json:"response_code"`
+	ResponseBody string    `json:"response_body"`
+	Error        string    `json:"error"`
+	ErrorClass   string    `json:"error_class"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// WebhookBreaker represents the state of a webhook's delivery circuit breaker.
+type WebhookBreaker struct {
+	WebhookID int    `json:"webhook_id"`
+	State     string `json:"state"`
+
+	// Receiver failures within the breaker's window while the circuit is closed.
+	Failures int `json:"failures"`
+
+	// When the circuit was last opened, and while it's open, when a probe will be attempted.
+	OpenedAt null.Time `json:"opened_at"`
+	RetryAt  null.Time `json:"retry_at"`
+}
+
+// WebhookLogExport represents a webhook log that's exported to CSV.
+type WebhookLogExport struct {
+	ID           int       `db:"id"`
+	WebhookName  string    `db:"webhook_name"`
+	Event        string    `db:"event"`
+	Status       string    `db:"status"`
+	ResponseCode int       `db:"response_code"`
+	Attempts     int       `db:"attempts"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// WebhookLogError represents a group of webhook logs with an identical error.
+type WebhookLogError struct {
+	WebhookID    int         `db:"webhook_id" json:"webhook_id"`
+	WebhookName  null.String `db:"webhook_name" json:"webhook_name"`
+	ResponseCode int         `db:"response_code" json:"response_code"`
+	ErrorClass   string      `db:"error_class" json:"error_class"`
+	Error        string      `db:"error" json:"error"`
+	Count        int         `db:"count" json:"count"`
+	FirstAt      time.Time   `db:"first_at" json:"first_at"`
+	LastAt       time.Time   `db:"last_at" json:"last_at"`
+}
+
+// WebhookDigest represents the delivery health of a webhook over a period.
+type WebhookDigest struct {
+	ID        int                 `db:"id" json:"id"`
+	Name      string              `db:"name" json:"name"`
+	Status    string              `db:"status" json:"status"`
+	Total     int                 `db:"total" json:"total"`
+	Success   int                 `db:"success" json:"success"`
+	Failed    int                 `db:"failed" json:"failed"`
+	Pending   int                 `db:"pending" json:"pending"`
+	Expired   int                 `db:"expired" json:"expired"`
+	TopErrors WebhookDigestErrors `db:"top_errors" json:"top_errors"`
+}
+
+// WebhookDigestErrors is the list of the most frequent errors of a webhook in a digest.
+type WebhookDigestErrors []struct {
+	Error string `json:"error"`
+	Count int    `json:"count"`
+}
+
+// Scan unmarshals JSON from the DB.
+func (w *WebhookDigestErrors) Scan(src any) error {
+	if src == nil {
+		*w = WebhookDigestErrors{}
+		return nil
+	}
+
+	if data, ok := src.([]byte); ok {
+		return json.Unmarshal(data, w)
+	}
+	return fmt.Errorf("could not not decode type %T -> %T", src, w)
+}
+
+// WebhookHeaders is the set of custom HTTP headers that are sent with a webhook's deliveries.
+type WebhookHeaders map[string]string
+
+// Scan unmarshals JSONB from the DB.
+func (h *WebhookHeaders) Scan(src any) error {
+	if src == nil {
+		*h = WebhookHeaders{}
+		return nil
+	}
+
+	if data, ok := src.([]byte); ok {
+		return json.Unmarshal(data, h)
+	}
+	return fmt.Errorf("could not not decode type %T -> %T", src, h)
+}
+
+// Value returns the JSON marshalled headers.
+func (h WebhookHeaders) Value() (driver.Value, error) {
+	if h == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(h)
+}
+
+// WebhookEvent is the JSON envelope that's posted to webhooks.
+type WebhookEvent struct {
+	// Unique ID of the message that's shared with the receiver for correlation.
+	MessageID string       `json:"message_id"`
+	Event     string       `json:"event"`
+	Timestamp time.Time    `json:"timestamp"`
+	Actor     WebhookActor `json:"actor"`
+	Data      any          `json:"data"`
+
+	// Set on test deliveries, which receivers should ignore.
+	Test bool `json:"test,omitempty"`
+}
+
+// WebhookActor represents the user, subscriber, or system process that triggered a webhook event.
+type WebhookActor struct {
+	Type string `json:"type"`
+	ID   int    `json:"id,omitempty"`
+	UUID string `json:"uuid,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// SystemWebhookActor is the actor of the events that aren't triggered by a request.
+var SystemWebhookActor = WebhookActor{Type: WebhookActorSystem}
+
+// UnpackPayload decompresses the log's payload into Payload if it's stored compressed.
+func (l *WebhookLog) UnpackPayload() error {
+	if len(l.PayloadGZ) == 0 {
+		return nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(l.PayloadGZ))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	l.Payload, l.PayloadGZ = b, nil
+	return nil
+}
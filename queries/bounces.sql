@@ -14,6 +14,7 @@ num AS (
 block1 AS (
     UPDATE subscribers SET status='blocklisted'
     WHERE $9 = 'blocklist' AND (SELECT num FROM num) >= $8 AND id = (SELECT id FROM sub) AND (SELECT status FROM sub) != 'blocklisted'
+    RETURNING uuid, email
 ),
 block2 AS (
     UPDATE subscriber_lists SET status='unsubscribed'
@@ -24,10 +25,14 @@ bounce AS (
     INSERT INTO bounces (subscriber_id, campaign_id, type, source, meta, created_at)
     SELECT (SELECT id FROM sub), (SELECT id FROM camp), $4, $5, $6, $7
     WHERE NOT EXISTS (SELECT 1 WHERE (SELECT status FROM sub) = 'blocklisted' OR (SELECT num FROM num) > $8)
-)
+),
 -- This delete  will only run when $9 = 'delete' and the number of bounces exceed $8.
-DELETE FROM subscribers
-    WHERE $9 = 'delete' AND (SELECT num FROM num) >= $8 AND id = (SELECT id FROM sub);
+del AS (
+    DELETE FROM subscribers
+    WHERE $9 = 'delete' AND (SELECT num FROM num) >= $8 AND id = (SELECT id FROM sub)
+)
+-- Return the subscriber if it was blocklisted by the bounce.
+SELECT uuid, email FROM block1;
 
 -- name: query-bounces
 SELECT COUNT(*) OVER () AS total,
@@ -64,13 +69,21 @@ WITH sub AS (
 DELETE FROM bounces WHERE subscriber_id = (SELECT id FROM sub);
 
 -- name: blocklist-bounced-subscribers
+-- Returns the subscribers that weren't already blocklisted.
 WITH subs AS (
     SELECT subscriber_id FROM bounces
 ),
+prev AS (
+    SELECT id FROM subscribers WHERE id = ANY(SELECT subscriber_id FROM subs) AND status != 'blocklisted'
+),
 b AS (
     UPDATE subscribers SET status='blocklisted', updated_at=NOW()
     WHERE id = ANY(SELECT subscriber_id FROM subs)
+    RETURNING id, uuid, email
+),
+l AS (
+    UPDATE subscriber_lists SET status='unsubscribed', updated_at=NOW()
+    WHERE subscriber_id = ANY(SELECT subscriber_id FROM subs)
 )
-UPDATE subscriber_lists SET status='unsubscribed', updated_at=NOW()
-    WHERE subscriber_id = ANY(SELECT subscriber_id FROM subs);
+SELECT uuid, email FROM b WHERE id IN (SELECT id FROM prev);
 
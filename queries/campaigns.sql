@@ -149,6 +149,11 @@ LEFT JOIN clicks AS c ON (c.campaign_id = id)
 LEFT JOIN bounces AS b ON (b.campaign_id = id)
 ORDER BY ARRAY_POSITION($1, id);
 
+-- name: get-campaign-lists
+-- The {id, name} pairs of a campaign's lists, as in get-campaign-stats, without the stats.
+SELECT COALESCE(JSON_AGG(JSON_BUILD_OBJECT('id', list_id, 'name', list_name)), '[]') FROM campaign_lists
+    WHERE campaign_id = $1;
+
 -- name: get-campaign-for-preview
 SELECT campaigns.*, COALESCE(templates.body, '') AS template_body,
 (
@@ -207,21 +207,33 @@ DELETE FROM subscribers a WHERE NOT EXISTS
     (SELECT 1 FROM subscriber_lists b WHERE b.subscriber_id = a.id);
 
 -- name: blocklist-subscribers
-WITH b AS (
+-- Returns the subscribers that weren't already blocklisted.
+WITH prev AS (
+    SELECT id FROM subscribers WHERE id = ANY($1::INT[]) AND status != 'blocklisted'
+),
+b AS (
     UPDATE subscribers SET status='blocklisted', updated_at=NOW()
     WHERE id = ANY($1::INT[])
+    RETURNING id, uuid, email
+),
+l AS (
+    UPDATE subscriber_lists SET status='unsubscribed', updated_at=NOW()
+    WHERE subscriber_id = ANY($1::INT[])
 )
-UPDATE subscriber_lists SET status='unsubscribed', updated_at=NOW()
-    WHERE subscriber_id = ANY($1::INT[]);
+SELECT uuid, email FROM b WHERE id IN (SELECT id FROM prev);
 
 -- name: add-subscribers-to-lists
+-- Returns the resulting subscriptions, whose status may be their existing one if $3 is empty.
 INSERT INTO subscriber_lists (subscriber_id, list_id, status)
     (SELECT a, b, (CASE WHEN $3 != '' THEN $3::subscription_status ELSE 'unconfirmed' END) FROM UNNEST($1::INT[]) a, UNNEST($2::INT[]) b)
-    ON CONFLICT (subscriber_id, list_id) DO UPDATE SET status=(CASE WHEN $3 != '' THEN $3::subscription_status ELSE subscriber_lists.status END);
+    ON CONFLICT (subscriber_id, list_id) DO UPDATE SET status=(CASE WHEN $3 != '' THEN $3::subscription_status ELSE subscriber_lists.status END)
+    RETURNING subscriber_id, list_id, status;
 
 -- name: delete-subscriptions
+-- Returns the deleted subscriptions with the status they had.
 DELETE FROM subscriber_lists
-    WHERE (subscriber_id, list_id) = ANY(SELECT a, b FROM UNNEST($1::INT[]) a, UNNEST($2::INT[]) b);
+    WHERE (subscriber_id, list_id) = ANY(SELECT a, b FROM UNNEST($1::INT[]) a, UNNEST($2::INT[]) b)
+    RETURNING subscriber_id, list_id, status;
 
 -- name: confirm-subscription-optin
 WITH subID AS (
@@ -259,7 +271,8 @@ sub AS (
 UPDATE subscriber_lists SET status = 'unsubscribed', updated_at=NOW() WHERE
     subscriber_id = (SELECT id FROM sub) AND status != 'unsubscribed' AND
     -- If $3 is false, unsubscribe from the campaign's lists, otherwise all lists.
-    CASE WHEN $3 IS FALSE THEN list_id = ANY(SELECT list_id FROM lists) ELSE list_id != 0 END;
+    CASE WHEN $3 IS FALSE THEN list_id = ANY(SELECT list_id FROM lists) ELSE list_id != 0 END
+    RETURNING list_id;
 
 -- name: delete-unconfirmed-subscriptions
 WITH optins AS (
@@ -367,13 +380,21 @@ DELETE FROM subscribers WHERE id=ANY(SELECT id FROM subs);
 
 -- name: blocklist-subscribers-by-query
 -- raw: true
+-- Returns the subscribers that weren't already blocklisted.
 WITH subs AS (%query%),
+prev AS (
+    SELECT id FROM subscribers WHERE id = ANY(SELECT id FROM subs) AND status != 'blocklisted'
+),
 b AS (
     UPDATE subscribers SET status='blocklisted', updated_at=NOW()
     WHERE id = ANY(SELECT id FROM subs)
+    RETURNING id, uuid, email
+),
+l AS (
+    UPDATE subscriber_lists SET status='unsubscribed', updated_at=NOW()
+    WHERE subscriber_id = ANY(SELECT id FROM subs)
 )
-UPDATE subscriber_lists SET status='unsubscribed', updated_at=NOW()
-    WHERE subscriber_id = ANY(SELECT id FROM subs);
+SELECT uuid, email FROM b WHERE id IN (SELECT id FROM prev);
 
 -- name: add-subscribers-to-lists-by-query
 -- raw: true
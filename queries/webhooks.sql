@@ -0,0 +1,492 @@
+-- webhooks
+-- name: get-webhooks
+-- The error of the most recent failed delivery attempt, if any, is joined as last_error.
+SELECT COUNT(*) OVER () AS total, webhooks.*, e.error AS last_error, e.updated_at AS last_error_at FROM webhooks
+    LEFT JOIN LATERAL (
+        SELECT error, updated_at FROM webhook_logs
+        WHERE webhook_id = webhooks.id AND error != ''
+        ORDER BY updated_at DESC LIMIT 1
+    ) e ON TRUE
+    WHERE ($1 = 0 OR webhooks.id = $1)
+    ORDER BY webhooks.id;
+
+-- name: get-webhooks-by-event
+-- Retrieves the enabled and paused webhooks that are subscribed to the given event, heaviest first,
+-- which get the event first if the number of webhooks per event is capped. Events are queued for
+-- paused webhooks and are delivered when they're resumed.
+SELECT * FROM webhooks WHERE status IN ('enabled', 'paused') AND $1 = ANY(events) ORDER BY weight DESC, id;
+
+-- name: create-webhook
+INSERT INTO webhooks (uuid, name, url, status, events, auth_type, auth_basic_user, auth_basic_pass, auth_hmac_secret, max_retries, timeout, auth_hmac_timestamp, max_event_age, debug, http_method, payload_version, charset, max_inflight_retries, empty_data, disable_keep_alive, success_body_regex, success_body_json, auth_hmac_sign_url, auth_basic_header, ca_bundle, insecure_skip_verify, content_type, payload_root_key, headers, retry_window, retry_window_tz, auth_hmac_ttl, hmac_algorithm, field_naming, backoff_strategy, retry_interval, failure_threshold, max_response_body, compress, weight, total_deadline, filter_expression, confirm_window, payload_format, signature_tolerance, list_ids)
+    VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36, $37, $38, $39, $40, $41, $42, $43, $44, $45, $46) RETURNING id;
+
+-- name: update-webhook
+-- Empty secrets retain the existing values.
+UPDATE webhooks SET
+    name = $2,
+    url = $3,
+    status = $4,
+    events = $5,
+    auth_type = $6,
+    auth_basic_user = $7,
+    auth_basic_pass = (CASE WHEN $8 != '' THEN $8 ELSE auth_basic_pass END),
+    auth_hmac_secret = (CASE WHEN $9 != '' THEN $9 ELSE auth_hmac_secret END),
+    max_retries = $10,
+    timeout = $11,
+    auth_hmac_timestamp = $12,
+    max_event_age = $13,
+    debug = $14,
+    http_method = $15,
+    payload_version = $16,
+    charset = $17,
+    max_inflight_retries = $18,
+    empty_data = $19,
+    disable_keep_alive = $20,
+    success_body_regex = $21,
+    success_body_json = $22,
+    auth_hmac_sign_url = $23,
+    auth_basic_header = $24,
+    ca_bundle = $25,
+    insecure_skip_verify = $26,
+    content_type = $27,
+    payload_root_key = $28,
+    headers = $29,
+    retry_window = $30,
+    retry_window_tz = $31,
+    auth_hmac_ttl = $32,
+    hmac_algorithm = $33,
+    field_naming = $34,
+    backoff_strategy = $35,
+    retry_interval = $36,
+    failure_threshold = $37,
+    max_response_body = $38,
+    compress = $39,
+    weight = $40,
+    total_deadline = $41,
+    filter_expression = $42,
+    confirm_window = $43,
+    payload_format = $44,
+    signature_tolerance = $45,
+    list_ids = $46,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: rotate-webhook-secret
+-- Replaces the HMAC secret of a webhook and retains the current one as the previous
+-- secret until $3 seconds from now. The previous secret of an earlier rotation is dropped.
+UPDATE webhooks SET
+    auth_hmac_secret_prev = auth_hmac_secret,
+    auth_hmac_secret = $2,
+    auth_hmac_rotated_at = NOW(),
+    auth_hmac_prev_expires_at = NOW() + MAKE_INTERVAL(secs => $3),
+    updated_at = NOW()
+WHERE id = $1 AND auth_type = 'hmac';
+
+-- name: finalize-webhook-secret-rotation
+-- Drops the previous HMAC secret of a rotation.
+UPDATE webhooks SET
+    auth_hmac_secret_prev = '',
+    auth_hmac_prev_expires_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: update-webhooks-status
+UPDATE webhooks SET status = $2, updated_at = NOW() WHERE id = ANY($1);
+
+-- name: delete-webhooks
+DELETE FROM webhooks WHERE id = ANY($1);
+
+-- name: create-webhook-audit
+INSERT INTO webhook_audit (webhook_id, action, changes, user_id, user_name)
+    VALUES($1, $2, $3, NULLIF($4, 0), COALESCE((SELECT username FROM users WHERE id = $4), ''));
+
+-- name: get-webhook-history
+SELECT COUNT(*) OVER () AS total, * FROM webhook_audit WHERE webhook_id = $1
+    ORDER BY id DESC OFFSET $2 LIMIT (CASE WHEN $3 < 1 THEN NULL ELSE $3 END);
+
+-- name: get-webhook-stats
+-- Retrieves the delivery backlog of every webhook. The age of the oldest undelivered
+-- (pending or retrying) log indicates whether deliveries are falling behind.
+SELECT webhooks.id, webhooks.name, webhooks.status,
+    COUNT(webhook_logs.id) AS pending,
+    COUNT(webhook_logs.id) FILTER (WHERE webhook_logs.attempts > 0) AS retrying,
+    MIN(webhook_logs.created_at) AS oldest_pending_at,
+    COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(webhook_logs.created_at)), 0)::BIGINT AS oldest_pending_age
+FROM webhooks
+LEFT JOIN webhook_logs ON (webhook_logs.webhook_id = webhooks.id AND webhook_logs.status = 'pending')
+GROUP BY webhooks.id
+ORDER BY webhooks.id;
+
+-- webhook logs
+-- name: create-webhook-log
+-- Queues a delivery log for a webhook and assigns it the next number
+-- in the webhook's log sequence. A compressed payload ($7) is stored in
+-- payload_gz, in which case, payload is empty. The log is due after $8 seconds.
+WITH seq AS (
+    UPDATE webhooks SET log_sequence = log_sequence + 1 WHERE id = $1 RETURNING log_sequence
+)
+INSERT INTO webhook_logs (webhook_id, event, idempotency_key, message_id, sequence, payload, test, payload_gz, next_retry_at)
+    VALUES($1, $2, $3, $4, (SELECT log_sequence FROM seq), $5, $6, $7, NOW() + MAKE_INTERVAL(secs => $8)) RETURNING id;
+
+-- name: create-webhook-logs
+-- Queues a batch of delivery logs given as arrays of their fields, and assigns them
+-- the next numbers in their webhooks' log sequences in the order of the arrays. Empty
+-- compressed payloads ($7) are stored as NULL, and logs are due after their delays ($8)
+-- in seconds. Logs of deleted webhooks are skipped.
+WITH logs AS (
+    SELECT * FROM UNNEST($1::INT[], $2::TEXT[], $3::UUID[], $4::UUID[], $5::JSONB[], $6::BOOLEAN[], $7::BYTEA[], $8::FLOAT8[])
+        WITH ORDINALITY AS l(webhook_id, event, idempotency_key, message_id, payload, test, payload_gz, delay, num)
+),
+counts AS (
+    SELECT webhook_id, COUNT(*) AS n FROM logs GROUP BY webhook_id
+),
+seq AS (
+    UPDATE webhooks SET log_sequence = log_sequence + counts.n
+    FROM counts WHERE webhooks.id = counts.webhook_id
+    RETURNING webhooks.id, webhooks.log_sequence - counts.n AS base
+)
+INSERT INTO webhook_logs (webhook_id, event, idempotency_key, message_id, sequence, payload, test, payload_gz, next_retry_at)
+    SELECT logs.webhook_id, logs.event, logs.idempotency_key, logs.message_id,
+        seq.base + ROW_NUMBER() OVER (PARTITION BY logs.webhook_id ORDER BY logs.num),
+        logs.payload, logs.test, NULLIF(logs.payload_gz, ''::BYTEA), NOW() + MAKE_INTERVAL(secs => logs.delay)
+    FROM logs JOIN seq ON (seq.id = logs.webhook_id)
+    ORDER BY logs.num;
+
+-- name: create-failed-webhook-log
+-- Records an event that couldn't be queued for delivery, eg: because its
+-- data couldn't be marshalled, as a permanently failed log.
+WITH seq AS (
+    UPDATE webhooks SET log_sequence = log_sequence + 1 WHERE id = $1 RETURNING log_sequence
+)
+INSERT INTO webhook_logs (webhook_id, event, status, idempotency_key, message_id, sequence, payload, error, error_class, next_retry_at)
+    VALUES($1, $2, 'failed', $3, $4, (SELECT log_sequence FROM seq), $5, $6, $7, NULL) RETURNING id;
+
+-- name: get-pending-webhook-logs
+-- Fetches a batch of logs that are due for delivery and leases them by pushing their
+-- next_retry_at forward so that other workers (or instances) don't pick them up.
+-- If a worker dies mid-delivery, the log becomes due again once the lease expires.
+-- Only max_inflight_retries of a webhook's logs are retried at once: the retrying logs
+-- that are already leased or waiting for their next attempt (next_retry_at in the future)
+-- by any worker or instance count towards it, and the rest are deferred to subsequent
+-- batches so that a flapping receiver isn't flooded with retries when it recovers.
+-- Worker pools pick only the logs of certain events ($3) or skip the logs of events ($4).
+-- Batches are shared between webhooks by weighted fair queuing: every due log gets a
+-- virtual finish time of its position in its webhook's queue divided by the webhook's
+-- weight, and logs are picked in the order of that time. A webhook with a weight of 3 thus
+-- gets ~3 logs into a batch for every log of a webhook with a weight of 1, and no webhook
+-- with due logs is starved by another's backlog.
+WITH inflight AS (
+    SELECT webhook_id, COUNT(*) AS n FROM webhook_logs
+    WHERE status = 'pending' AND attempts > 0 AND next_retry_at > NOW()
+    GROUP BY webhook_id
+),
+due AS (
+    SELECT webhook_logs.id, webhook_logs.attempts,
+        webhooks.max_inflight_retries - COALESCE(inflight.n, 0) AS max_retries,
+        ROW_NUMBER() OVER (PARTITION BY webhook_logs.webhook_id, webhook_logs.attempts > 0
+            ORDER BY webhook_logs.next_retry_at, webhook_logs.id) AS num,
+        ROW_NUMBER() OVER (PARTITION BY webhook_logs.webhook_id
+            ORDER BY webhook_logs.next_retry_at, webhook_logs.id)::FLOAT / GREATEST(webhooks.weight, 1) AS finish
+    FROM webhook_logs
+    JOIN webhooks ON (webhooks.id = webhook_logs.webhook_id)
+    LEFT JOIN inflight ON (inflight.webhook_id = webhook_logs.webhook_id)
+    WHERE webhook_logs.status = 'pending' AND webhook_logs.next_retry_at <= NOW()
+        -- Logs of paused (and disabled) webhooks stay pending until they're resumed.
+        AND webhooks.status = 'enabled'
+        AND (COALESCE(CARDINALITY($3::TEXT[]), 0) = 0 OR webhook_logs.event = ANY($3::TEXT[]))
+        AND NOT (webhook_logs.event = ANY(COALESCE($4::TEXT[], '{}')))
+),
+logs AS (
+    SELECT webhook_logs.id FROM webhook_logs
+    JOIN due ON (due.id = webhook_logs.id)
+    WHERE due.attempts = 0 OR due.num <= due.max_retries
+    ORDER BY due.finish, webhook_logs.next_retry_at, webhook_logs.id
+    LIMIT $1
+    FOR UPDATE OF webhook_logs SKIP LOCKED
+)
+UPDATE webhook_logs SET next_retry_at = NOW() + MAKE_INTERVAL(secs => $2)
+    FROM logs, webhooks
+    WHERE webhook_logs.id = logs.id AND webhooks.id = webhook_logs.webhook_id
+    RETURNING webhook_logs.*, ROW_TO_JSON(webhooks) AS webhook;
+
+-- name: update-webhook-log-success
+-- Records a successful attempt. If the delivery has to be confirmed by the receiver
+-- by a time ($9), the log stays pending until then, after which it's redelivered.
+-- Logs that have been confirmed in the meantime aren't changed.
+UPDATE webhook_logs SET
+    status = (CASE WHEN $9::TIMESTAMP WITH TIME ZONE IS NULL THEN 'success' ELSE 'pending' END)::webhook_log_status,
+    attempts = $2,
+    response_code = $3,
+    response_body = $4,
+    payload_version = $5,
+    url = $6,
+    response_hash = $7,
+    response_truncated = $8,
+    error = '',
+    error_class = '',
+    next_retry_at = $9,
+    confirm_by = $9,
+    updated_at = NOW()
+WHERE id = $1 AND confirmed_at IS NULL;
+
+-- name: set-webhook-log-confirm-token
+-- Stores the confirmation token of a log and clears the confirmation window of its
+-- previous delivery before it's delivered, so that the receiver can confirm it while
+-- the delivery is still in flight.
+UPDATE webhook_logs SET confirm_token = $2, confirm_by = NULL WHERE id = $1;
+
+-- name: confirm-webhook-log
+-- Marks a delivered log as confirmed by its receiver if the token matches and it's
+-- within the confirmation window, or the delivery is in flight (no confirm_by yet).
+UPDATE webhook_logs SET
+    status = 'success',
+    confirmed_at = NOW(),
+    confirm_by = NULL,
+    next_retry_at = NULL,
+    updated_at = NOW()
+WHERE message_id = $1
+    AND confirm_token = $2
+    AND confirm_token != ''
+    AND status = 'pending'
+    AND confirmed_at IS NULL
+    AND (confirm_by IS NULL OR confirm_by > NOW())
+RETURNING id;
+
+-- name: update-webhook-log-failed
+-- Records a failed attempt. If there's no next retry time ($6), the log
+-- is marked as permanently failed, or as expired if it's past its webhook's
+-- delivery deadline ($12).
+UPDATE webhook_logs SET
+    status = (CASE WHEN $6::TIMESTAMP WITH TIME ZONE IS NOT NULL THEN 'pending'
+        WHEN $12::BOOLEAN THEN 'expired' ELSE 'failed' END)::webhook_log_status,
+    attempts = $2,
+    response_code = $3,
+    response_body = $4,
+    error = $5,
+    next_retry_at = $6,
+    payload_version = $7,
+    error_class = $8,
+    url = $9,
+    response_hash = $10,
+    response_truncated = $11,
+    confirm_by = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND confirmed_at IS NULL;
+
+-- name: append-webhook-log-attempt
+-- Appends a failed attempt ($2) to a log's attempt history.
+UPDATE webhook_logs SET attempt_history = attempt_history || JSONB_BUILD_ARRAY($2::JSONB) WHERE id = $1;
+
+-- name: record-webhook-failure
+-- Counts a permanently failed delivery towards a webhook's consecutive failures and
+-- disables the webhook if the count reaches its failure threshold (0 = never).
+UPDATE webhooks SET
+    consecutive_failures = consecutive_failures + 1,
+    status = (CASE WHEN failure_threshold > 0 AND consecutive_failures + 1 >= failure_threshold
+        THEN 'disabled' ELSE status END)::webhook_status
+WHERE id = $1
+RETURNING consecutive_failures, failure_threshold;
+
+-- name: reset-webhook-failures
+UPDATE webhooks SET consecutive_failures = 0 WHERE id = ANY($1::INT[]) AND consecutive_failures > 0;
+
+-- name: update-webhook-log-expired
+-- Marks a log that sat in the queue for longer than its webhook's max event age,
+-- or that's past its webhook's delivery deadline, as expired.
+UPDATE webhook_logs SET
+    status = 'expired',
+    error = $2,
+    next_retry_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: defer-webhook-log
+-- Reschedules a pending log, eg: while its webhook's circuit is open, without counting an attempt.
+UPDATE webhook_logs SET next_retry_at = $2, updated_at = NOW() WHERE id = $1 AND status = 'pending';
+
+-- name: query-webhook-logs
+-- $11 and $12 are an optional creation time range. $13 is an optional ILIKE pattern that's
+-- matched against the error and the response body, which is a sequential scan of the logs
+-- that match the other filters as neither is indexed.
+SELECT COUNT(*) OVER () AS total,
+    webhook_logs.*,
+    webhooks.name AS webhook_name
+FROM webhook_logs
+LEFT JOIN webhooks ON (webhooks.id = webhook_logs.webhook_id)
+WHERE ($1 = 0 OR webhook_logs.id = $1)
+    AND ($2 = 0 OR webhook_logs.webhook_id = $2)
+    AND ($3 = '' OR webhook_logs.status = $3::webhook_log_status)
+    AND ($4 = '' OR webhook_logs.event = $4)
+    AND ($5 = '' OR webhook_logs.idempotency_key = $5::UUID)
+    AND ($6 = 0 OR webhook_logs.sequence = $6)
+    AND ($7 = '' OR webhook_logs.message_id = $7::UUID)
+    AND (COALESCE(CARDINALITY($8::TEXT[]), 0) = 0 OR webhook_logs.event = ANY($8::TEXT[]))
+    AND ($11::TIMESTAMP WITH TIME ZONE IS NULL OR webhook_logs.created_at >= $11)
+    AND ($12::TIMESTAMP WITH TIME ZONE IS NULL OR webhook_logs.created_at <= $12)
+    AND ($13 = '' OR webhook_logs.error ILIKE $13 OR webhook_logs.response_body ILIKE $13)
+ORDER BY %order% OFFSET $9 LIMIT (CASE WHEN $10 < 1 THEN NULL ELSE $10 END);
+
+-- name: export-webhook-logs
+-- Fetches a batch of $9 logs after the log ID $1 for exporting, with the same filters as
+-- query-webhook-logs, including the optional creation time range ($6 - $7).
+SELECT webhook_logs.id, COALESCE(webhooks.name, '') AS webhook_name, webhook_logs.event,
+    webhook_logs.status, webhook_logs.response_code, webhook_logs.attempts, webhook_logs.created_at
+FROM webhook_logs
+LEFT JOIN webhooks ON (webhooks.id = webhook_logs.webhook_id)
+WHERE webhook_logs.id > $1
+    AND ($2 = 0 OR webhook_logs.webhook_id = $2)
+    AND ($3 = '' OR webhook_logs.status = $3::webhook_log_status)
+    AND ($4 = '' OR webhook_logs.event = $4)
+    AND (COALESCE(CARDINALITY($5::TEXT[]), 0) = 0 OR webhook_logs.event = ANY($5::TEXT[]))
+    AND ($6::TIMESTAMP WITH TIME ZONE IS NULL OR webhook_logs.created_at >= $6)
+    AND ($7::TIMESTAMP WITH TIME ZONE IS NULL OR webhook_logs.created_at <= $7)
+    AND ($8 = '' OR webhook_logs.message_id = $8::UUID)
+ORDER BY webhook_logs.id LIMIT $9;
+
+-- name: retry-webhook-log
+-- Moves a failed or expired log back to pending for immediate delivery. Its attempts
+-- are incremented when the retry runs. The webhook's max event age and delivery
+-- deadline count from requeued_at instead of the log's creation.
+UPDATE webhook_logs SET
+    status = 'pending',
+    next_retry_at = NOW(),
+    requeued_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND status IN ('failed', 'expired') AND error_class != 'payload';
+
+-- name: retry-webhook-logs
+-- Moves the failed and expired logs that match the filters back to pending for immediate
+-- delivery with a fresh set of attempts. $4 and $5 are an optional range of response codes
+-- and $6 an optional creation time since which logs are retried. Logs of events whose
+-- data couldn't be marshalled have no data to deliver and aren't retried. Returns the
+-- number of logs retried per webhook.
+WITH logs AS (
+    UPDATE webhook_logs SET
+        status = 'pending',
+        attempts = 0,
+        next_retry_at = NOW(),
+        requeued_at = NOW(),
+        updated_at = NOW()
+    WHERE status IN ('failed', 'expired')
+        AND error_class != 'payload'
+        AND ($1 = 0 OR webhook_id = $1)
+        AND ($2 = '' OR status = $2::webhook_log_status)
+        AND ($3 = '' OR event = $3)
+        AND ($4 = 0 OR response_code >= $4)
+        AND ($5 = 0 OR response_code <= $5)
+        AND ($6::TIMESTAMP WITH TIME ZONE IS NULL OR created_at >= $6)
+    RETURNING webhook_id
+)
+SELECT webhook_id, COUNT(*) AS count FROM logs GROUP BY webhook_id;
+
+-- name: get-dead-letter-logs
+-- Dead letters are the failed logs that have exhausted their webhook's retries.
+SELECT COUNT(*) OVER () AS total,
+    webhook_logs.*,
+    webhooks.name AS webhook_name
+FROM webhook_logs
+JOIN webhooks ON (webhooks.id = webhook_logs.webhook_id)
+WHERE webhook_logs.status = 'failed'
+    AND webhook_logs.attempts > webhooks.max_retries
+    AND ($1 = 0 OR webhook_logs.webhook_id = $1)
+ORDER BY webhook_logs.updated_at DESC, webhook_logs.id DESC
+OFFSET $2 LIMIT (CASE WHEN $3 < 1 THEN NULL ELSE $3 END);
+
+-- name: resubmit-dead-letters
+-- Moves the given dead letters back to pending for immediate delivery with a fresh
+-- set of attempts. Logs that aren't dead letters (anymore) are skipped. Returns the
+-- number of logs resubmitted per webhook.
+WITH logs AS (
+    UPDATE webhook_logs SET
+        status = 'pending',
+        attempts = 0,
+        next_retry_at = NOW(),
+        requeued_at = NOW(),
+        updated_at = NOW()
+    FROM webhooks
+    WHERE webhook_logs.id = ANY($1::INT[])
+        AND webhooks.id = webhook_logs.webhook_id
+        AND webhook_logs.status = 'failed'
+        AND webhook_logs.error_class != 'payload'
+        AND webhook_logs.attempts > webhooks.max_retries
+    RETURNING webhook_logs.webhook_id
+)
+SELECT webhook_id, COUNT(*) AS count FROM logs GROUP BY webhook_id;
+
+-- name: get-webhook-log-errors
+-- Groups the logs with identical errors with their counts, so that a long outage
+-- of a receiver shows up as a few rows instead of thousands of logs.
+SELECT webhook_logs.webhook_id,
+    webhooks.name AS webhook_name,
+    webhook_logs.response_code,
+    webhook_logs.error_class,
+    webhook_logs.error,
+    COUNT(*) AS count,
+    MIN(webhook_logs.created_at) AS first_at,
+    MAX(webhook_logs.updated_at) AS last_at
+FROM webhook_logs
+LEFT JOIN webhooks ON (webhooks.id = webhook_logs.webhook_id)
+WHERE webhook_logs.error != ''
+    AND ($1 = 0 OR webhook_logs.webhook_id = $1)
+    AND ($2 = '' OR webhook_logs.status = $2::webhook_log_status)
+GROUP BY webhook_logs.webhook_id, webhooks.name, webhook_logs.response_code, webhook_logs.error_class, webhook_logs.error
+ORDER BY count DESC, last_at DESC;
+
+-- name: get-webhook-digest
+-- Summarizes the delivery health of every webhook over the last period ($1 seconds)
+-- with the counts of logs by status and the top errors.
+WITH logs AS (
+    SELECT * FROM webhook_logs WHERE updated_at >= NOW() - MAKE_INTERVAL(secs => $1)
+),
+errs AS (
+    SELECT webhook_id, error, COUNT(*) AS count,
+        ROW_NUMBER() OVER (PARTITION BY webhook_id ORDER BY COUNT(*) DESC) AS num
+    FROM logs WHERE error != ''
+    GROUP BY webhook_id, error
+)
+SELECT webhooks.id, webhooks.name, webhooks.status,
+    COUNT(logs.id) AS total,
+    COUNT(logs.id) FILTER (WHERE logs.status = 'success') AS success,
+    COUNT(logs.id) FILTER (WHERE logs.status = 'failed') AS failed,
+    COUNT(logs.id) FILTER (WHERE logs.status = 'pending') AS pending,
+    COUNT(logs.id) FILTER (WHERE logs.status = 'expired') AS expired,
+    COALESCE((
+        SELECT JSON_AGG(JSON_BUILD_OBJECT('error', errs.error, 'count', errs.count) ORDER BY errs.count DESC)
+        FROM errs WHERE errs.webhook_id = webhooks.id AND errs.num <= 5
+    ), '[]') AS top_errors
+FROM webhooks
+LEFT JOIN logs ON (logs.webhook_id = webhooks.id)
+GROUP BY webhooks.id
+ORDER BY webhooks.id;
+
+-- name: delete-webhook-logs
+DELETE FROM webhook_logs WHERE $2 = TRUE OR id = ANY($1);
+
+-- name: delete-webhook-logs-by-filter
+-- Deletes the delivered (non-pending) logs that match the filters, which are the same
+-- as those of retry-webhook-logs. Pending logs are never deleted.
+DELETE FROM webhook_logs
+WHERE status != 'pending'
+    AND ($1 = 0 OR webhook_id = $1)
+    AND ($2 = '' OR status = $2::webhook_log_status)
+    AND ($3 = '' OR event = $3)
+    AND ($4 = 0 OR response_code >= $4)
+    AND ($5 = 0 OR response_code <= $5)
+    AND ($6::TIMESTAMP WITH TIME ZONE IS NULL OR created_at >= $6);
+
+-- name: prune-webhook-logs
+-- Deletes delivered (non-pending) logs that are older than $1 seconds if they're successful,
+-- or $3 seconds if they failed or expired, or that are beyond the most recent $2 logs of
+-- their webhook, whichever prunes more. 0 disables any of them.
+WITH ranked AS (
+    SELECT id, status, created_at,
+        ROW_NUMBER() OVER (PARTITION BY webhook_id ORDER BY created_at DESC, id DESC) AS num
+    FROM webhook_logs WHERE status != 'pending'
+)
+DELETE FROM webhook_logs USING ranked
+    WHERE webhook_logs.id = ranked.id
+    AND (
+        (ranked.status = 'success' AND $1 > 0 AND ranked.created_at < NOW() - MAKE_INTERVAL(secs => $1))
+        OR (ranked.status != 'success' AND $3 > 0 AND ranked.created_at < NOW() - MAKE_INTERVAL(secs => $3))
+        OR ($2 > 0 AND ranked.num > $2)
+    );
@@ -12,6 +12,9 @@ DROP TYPE IF EXISTS user_type CASCADE; CREATE TYPE user_type AS ENUM ('user', 'a
 DROP TYPE IF EXISTS user_status CASCADE; CREATE TYPE user_status AS ENUM ('enabled', 'disabled');
 DROP TYPE IF EXISTS role_type CASCADE; CREATE TYPE role_type AS ENUM ('user', 'list');
 DROP TYPE IF EXISTS twofa_type CASCADE; CREATE TYPE twofa_type AS ENUM ('none', 'totp');
+DROP TYPE IF EXISTS webhook_status CASCADE; CREATE TYPE webhook_status AS ENUM ('enabled', 'disabled', 'paused');
+DROP TYPE IF EXISTS webhook_auth_type CASCADE; CREATE TYPE webhook_auth_type AS ENUM ('none', 'basic', 'hmac');
+DROP TYPE IF EXISTS webhook_log_status CASCADE; CREATE TYPE webhook_log_status AS ENUM ('pending', 'success', 'failed', 'expired');
 
 CREATE EXTENSION IF NOT EXISTS pgcrypto;
 
@@ -312,6 +315,104 @@ DROP INDEX IF EXISTS idx_bounces_camp_id; CREATE INDEX idx_bounces_camp_id ON bo
 DROP INDEX IF EXISTS idx_bounces_source; CREATE INDEX idx_bounces_source ON bounces(source);
 DROP INDEX IF EXISTS idx_bounces_date; CREATE INDEX idx_bounces_date ON bounces((TIMEZONE('UTC', created_at)::DATE));
 
+-- webhooks
+DROP TABLE IF EXISTS webhooks CASCADE;
+CREATE TABLE webhooks (
+    id               SERIAL PRIMARY KEY,
+    uuid uuid        NOT NULL UNIQUE,
+    name             TEXT NOT NULL,
+    url              TEXT NOT NULL,
+    status           webhook_status NOT NULL DEFAULT 'enabled',
+    events           TEXT[] NOT NULL DEFAULT '{}',
+    list_ids         INTEGER[] NOT NULL DEFAULT '{}',
+    auth_type        webhook_auth_type NOT NULL DEFAULT 'none',
+    auth_basic_user  TEXT NOT NULL DEFAULT '',
+    auth_basic_pass  TEXT NOT NULL DEFAULT '',
+    auth_hmac_secret TEXT NOT NULL DEFAULT '',
+    max_retries      INTEGER NOT NULL DEFAULT 3,
+    timeout          TEXT NOT NULL DEFAULT '10s',
+    auth_hmac_timestamp TEXT NOT NULL DEFAULT 'send',
+    max_event_age    TEXT NOT NULL DEFAULT '',
+    debug            BOOLEAN NOT NULL DEFAULT false,
+    http_method      TEXT NOT NULL DEFAULT 'POST',
+    payload_version  TEXT NOT NULL DEFAULT '',
+    charset          TEXT NOT NULL DEFAULT '',
+    max_inflight_retries INTEGER NOT NULL DEFAULT 10,
+    empty_data       TEXT NOT NULL DEFAULT 'null',
+    disable_keep_alive BOOLEAN NOT NULL DEFAULT false,
+    success_body_regex TEXT NOT NULL DEFAULT '',
+    success_body_json TEXT NOT NULL DEFAULT '',
+    auth_hmac_sign_url TEXT NOT NULL DEFAULT 'none',
+    auth_basic_header TEXT NOT NULL DEFAULT 'Authorization',
+    ca_bundle        TEXT NOT NULL DEFAULT '',
+    insecure_skip_verify BOOLEAN NOT NULL DEFAULT false,
+    content_type     TEXT NOT NULL DEFAULT '',
+    payload_root_key TEXT NOT NULL DEFAULT '',
+    headers          JSONB NOT NULL DEFAULT '{}',
+    retry_window     TEXT NOT NULL DEFAULT '',
+    retry_window_tz  TEXT NOT NULL DEFAULT '',
+    auth_hmac_ttl    TEXT NOT NULL DEFAULT '',
+    hmac_algorithm   TEXT NOT NULL DEFAULT 'sha256',
+    field_naming     TEXT NOT NULL DEFAULT 'snake',
+    backoff_strategy TEXT NOT NULL DEFAULT 'exponential',
+    retry_interval   TEXT NOT NULL DEFAULT '',
+    failure_threshold INTEGER NOT NULL DEFAULT 0,
+    max_response_body INTEGER NOT NULL DEFAULT 0,
+    compress         BOOLEAN NOT NULL DEFAULT false,
+    weight           INTEGER NOT NULL DEFAULT 1,
+    total_deadline   TEXT NOT NULL DEFAULT '',
+    filter_expression TEXT NOT NULL DEFAULT '',
+    confirm_window   TEXT NOT NULL DEFAULT '',
+    payload_format   TEXT NOT NULL DEFAULT 'listmonk',
+    signature_tolerance TEXT NOT NULL DEFAULT '5m',
+    log_sequence     BIGINT NOT NULL DEFAULT 0,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    auth_hmac_secret_prev TEXT NOT NULL DEFAULT '',
+    auth_hmac_rotated_at TIMESTAMP WITH TIME ZONE NULL,
+    auth_hmac_prev_expires_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_webhooks_events; CREATE INDEX idx_webhooks_events ON webhooks USING GIN(events);
+DROP INDEX IF EXISTS idx_webhooks_list_ids; CREATE INDEX idx_webhooks_list_ids ON webhooks USING GIN(list_ids);
+
+-- webhook delivery logs
+DROP TABLE IF EXISTS webhook_logs CASCADE;
+CREATE TABLE webhook_logs (
+    id               BIGSERIAL PRIMARY KEY,
+    webhook_id       INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    event            TEXT NOT NULL,
+    status           webhook_log_status NOT NULL DEFAULT 'pending',
+    idempotency_key  uuid NOT NULL UNIQUE,
+    message_id       uuid NOT NULL UNIQUE,
+    sequence         BIGINT NOT NULL DEFAULT 0,
+    payload          JSONB NOT NULL DEFAULT '{}',
+    payload_gz       BYTEA NULL,
+    payload_version  TEXT NOT NULL DEFAULT '',
+    url              TEXT NOT NULL DEFAULT '',
+    test             BOOLEAN NOT NULL DEFAULT false,
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    response_code    INTEGER NOT NULL DEFAULT 0,
+    response_body    TEXT NOT NULL DEFAULT '',
+    response_hash    TEXT NOT NULL DEFAULT '',
+    response_truncated BOOLEAN NOT NULL DEFAULT false,
+    attempt_history  JSONB NOT NULL DEFAULT '[]',
+    confirm_token    TEXT NOT NULL DEFAULT '',
+    confirm_by       TIMESTAMP WITH TIME ZONE NULL,
+    confirmed_at     TIMESTAMP WITH TIME ZONE NULL,
+    error            TEXT NOT NULL DEFAULT '',
+    error_class      TEXT NOT NULL DEFAULT '',
+    next_retry_at    TIMESTAMP WITH TIME ZONE NULL DEFAULT NOW(),
+    requeued_at      TIMESTAMP WITH TIME ZONE NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_webhook_logs_webhook_id; CREATE INDEX idx_webhook_logs_webhook_id ON webhook_logs(webhook_id);
+DROP INDEX IF EXISTS idx_webhook_logs_sequence; CREATE INDEX idx_webhook_logs_sequence ON webhook_logs(webhook_id, sequence);
+DROP INDEX IF EXISTS idx_webhook_logs_event; CREATE INDEX idx_webhook_logs_event ON webhook_logs(event);
+DROP INDEX IF EXISTS idx_webhook_logs_pending; CREATE INDEX idx_webhook_logs_pending ON webhook_logs(status, next_retry_at);
+DROP INDEX IF EXISTS idx_webhook_logs_date; CREATE INDEX idx_webhook_logs_date ON webhook_logs((TIMEZONE('UTC', created_at)::DATE));
+
 -- roles
 DROP TABLE IF EXISTS roles CASCADE;
 CREATE TABLE roles (
@@ -348,6 +449,19 @@ CREATE TABLE users (
     updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- webhook configuration audit trail. Rows outlive deleted webhooks.
+DROP TABLE IF EXISTS webhook_audit CASCADE;
+CREATE TABLE webhook_audit (
+    id               BIGSERIAL PRIMARY KEY,
+    webhook_id       INTEGER NOT NULL,
+    action           TEXT NOT NULL,
+    changes          JSONB NOT NULL DEFAULT '{}',
+    user_id          INTEGER NULL REFERENCES users(id) ON DELETE SET NULL ON UPDATE CASCADE,
+    user_name        TEXT NOT NULL DEFAULT '',
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_webhook_audit_webhook_id; CREATE INDEX idx_webhook_audit_webhook_id ON webhook_audit(webhook_id);
+
 -- user sessions
 DROP TABLE IF EXISTS sessions CASCADE;
 CREATE TABLE sessions (